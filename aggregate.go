@@ -0,0 +1,194 @@
+package ndgo
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// --------------------------------------- aggregation queries ---------------------------------------
+
+// CountQuery counts nodes. Run it with Run, or use it as QueryDQL.
+type CountQuery QueryDQL
+
+// CountPredQuery counts values or edges of a predicate, per node
+type CountPredQuery QueryDQL
+
+// AggregateQuery aggregates values of a predicate with min, max, sum or avg
+type AggregateQuery QueryDQL
+
+// GroupByQuery counts nodes grouped by values or edges of a predicate
+type GroupByQuery QueryDQL
+
+// Count Usage: n, err := ndgo.Query{}.Count("type(Person)", "").Run(txn)
+// fx is the root function, filter is an optional @filter, i.e. `eq(name, "x")`.
+func (Query) Count(fx, filter string) CountQuery {
+	return CountQuery(fmt.Sprintf("{\n  q(func: %s)%s {\n    count(uid)\n  }\n}", fx, filterDirective(filter)))
+}
+
+// CountPred Usage: counts, err := ndgo.Query{}.CountPred("type(Person)", "", "friend").Run(txn)
+func (Query) CountPred(fx, filter, pred string) CountPredQuery {
+	return CountPredQuery(fmt.Sprintf("{\n  q(func: %s)%s {\n    uid\n    count: count(%s)\n  }\n}", fx, filterDirective(filter), pred))
+}
+
+// Min Usage: min, err := ndgo.Query{}.Min("type(Person)", "", "age").Run(txn)
+func (Query) Min(fx, filter, pred string) AggregateQuery {
+	return aggregateQuery("min", fx, filter, pred)
+}
+
+// Max Usage: max, err := ndgo.Query{}.Max("type(Person)", "", "age").Run(txn)
+func (Query) Max(fx, filter, pred string) AggregateQuery {
+	return aggregateQuery("max", fx, filter, pred)
+}
+
+// Sum Usage: sum, err := ndgo.Query{}.Sum("type(Person)", "", "age").Run(txn)
+func (Query) Sum(fx, filter, pred string) AggregateQuery {
+	return aggregateQuery("sum", fx, filter, pred)
+}
+
+// Avg Usage: avg, err := ndgo.Query{}.Avg("type(Person)", "", "age").Run(txn)
+func (Query) Avg(fx, filter, pred string) AggregateQuery {
+	return aggregateQuery("avg", fx, filter, pred)
+}
+
+// GroupBy Usage: counts, err := ndgo.Query{}.GroupBy("type(Person)", "", "city").Run(txn)
+func (Query) GroupBy(fx, filter, pred string) GroupByQuery {
+	return GroupByQuery(fmt.Sprintf("{\n  q(func: %s)%s @groupby(%s) {\n    count(uid)\n  }\n}", fx, filterDirective(filter), pred))
+}
+
+func aggregateQuery(fn, fx, filter, pred string) AggregateQuery {
+	return AggregateQuery(fmt.Sprintf("{\n  var(func: %s)%s {\n    v as %s\n  }\n  q() {\n    value: %s(val(v))\n  }\n}", fx, filterDirective(filter), pred, fn))
+}
+
+func filterDirective(filter string) string {
+	if filter == "" {
+		return ""
+	}
+	return " @filter(" + filter + ")"
+}
+
+// --------------------------------------- run ---------------------------------------
+
+// Run returns number of nodes
+func (v CountQuery) Run(t *Txn) (int, error) {
+	var decode struct {
+		Q []struct {
+			Count int `json:"count"`
+		} `json:"q"`
+	}
+	if err := runDecode(t, QueryDQL(v), &decode); err != nil {
+		return 0, err
+	}
+	if len(decode.Q) == 0 {
+		return 0, nil
+	}
+	return decode.Q[0].Count, nil
+}
+
+// Run returns counts by uid
+func (v CountPredQuery) Run(t *Txn) (map[string]int, error) {
+	var decode struct {
+		Q []struct {
+			UID   string `json:"uid"`
+			Count int    `json:"count"`
+		} `json:"q"`
+	}
+	if err := runDecode(t, QueryDQL(v), &decode); err != nil {
+		return nil, err
+	}
+	res := make(map[string]int, len(decode.Q))
+	for _, node := range decode.Q {
+		res[node.UID] = node.Count
+	}
+	return res, nil
+}
+
+// Run returns aggregate of int or float values, or 0 if there are none
+func (v AggregateQuery) Run(t *Txn) (float64, error) {
+	val, err := v.RunString(t)
+	if err != nil || val == "" {
+		return 0, err
+	}
+	f, err := strconv.ParseFloat(val, 64)
+	if err != nil {
+		return 0, fmt.Errorf("ndgo: aggregate is not a number: %s", val)
+	}
+	return f, nil
+}
+
+// RunString returns aggregate formatted as string, for min and max of string or datetime values,
+// or empty string if there are none
+func (v AggregateQuery) RunString(t *Txn) (string, error) {
+	var decode struct {
+		Q []struct {
+			Value json.RawMessage `json:"value"`
+		} `json:"q"`
+	}
+	if err := runDecode(t, QueryDQL(v), &decode); err != nil {
+		return "", err
+	}
+	if len(decode.Q) == 0 || decode.Q[0].Value == nil || string(decode.Q[0].Value) == "null" {
+		return "", nil
+	}
+	return scalarString(decode.Q[0].Value)
+}
+
+// Run returns counts by value of the predicate, or by uid for uid predicates
+func (v GroupByQuery) Run(t *Txn) (map[string]int, error) {
+	var decode struct {
+		Q []struct {
+			Groups []map[string]json.RawMessage `json:"@groupby"`
+		} `json:"q"`
+	}
+	if err := runDecode(t, QueryDQL(v), &decode); err != nil {
+		return nil, err
+	}
+	res := map[string]int{}
+	for _, q := range decode.Q {
+		for _, group := range q.Groups {
+			var count int
+			var key string
+			for k, raw := range group {
+				var err error
+				if k == "count" {
+					err = json.Unmarshal(raw, &count)
+				} else {
+					key, err = scalarString(raw)
+				}
+				if err != nil {
+					return nil, fmt.Errorf("ndgo: groupby %s: %w", k, err)
+				}
+			}
+			res[key] += count
+		}
+	}
+	return res, nil
+}
+
+func runDecode(t *Txn, q QueryDQL, decode interface{}) error {
+	resp, err := q.Run(t)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(resp.GetJson(), decode)
+}
+
+// scalarString formats json string, number or bool as string
+func scalarString(raw json.RawMessage) (string, error) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var val interface{}
+	if err := dec.Decode(&val); err != nil {
+		return "", err
+	}
+	switch val := val.(type) {
+	case string:
+		return val, nil
+	case json.Number:
+		return val.String(), nil
+	case bool:
+		return strconv.FormatBool(val), nil
+	}
+	return "", fmt.Errorf("unexpected value %s", raw)
+}
@@ -0,0 +1,55 @@
+package ndgo_test
+
+import (
+	"testing"
+
+	"github.com/ppp225/ndgo/v5"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAggregateQueries(t *testing.T) {
+	var testData = []struct {
+		q   string
+		out string
+	}{
+		{q: string(ndgo.Query{}.Count("type(Person)", "")), out: "{\n  q(func: type(Person)) {\n    count(uid)\n  }\n}"},
+		{q: string(ndgo.Query{}.CountPred("type(Person)", `eq(name, "x")`, "friend")), out: "{\n  q(func: type(Person)) @filter(eq(name, \"x\")) {\n    uid\n    count: count(friend)\n  }\n}"},
+		{q: string(ndgo.Query{}.Avg("has(age)", "", "age")), out: "{\n  var(func: has(age)) {\n    v as age\n  }\n  q() {\n    value: avg(val(v))\n  }\n}"},
+		{q: string(ndgo.Query{}.GroupBy("type(Person)", "has(city)", "city")), out: "{\n  q(func: type(Person)) @filter(has(city)) @groupby(city) {\n    count(uid)\n  }\n}"},
+	}
+	for _, tt := range testData {
+		require.Equal(t, tt.out, tt.q)
+		_, err := ndgo.ParseDQL(tt.q)
+		require.NoError(t, err)
+	}
+}
+
+func TestTxnAggregate(t *testing.T) {
+	dg := dgNewClient()
+	defer setupTeardown(dg)()
+	txn := ndgo.NewTxnWithoutContext(dg.NewTxn())
+	defer txn.Discard()
+	uid1, _, _, _ := populateDBComplex(txn, t)
+	fx := "type(" + testType + ")"
+
+	count, err := ndgo.Query{}.Count(fx, "").Run(txn)
+	require.NoError(t, err)
+	require.Equal(t, 4, count)
+	count, err = ndgo.Query{}.Count(fx, `eq(`+predicateName+`, "`+thirdName+`")`).Run(txn)
+	require.NoError(t, err)
+	require.Equal(t, 2, count)
+
+	edges, err := ndgo.Query{}.CountPred("uid("+uid1+")", "", predicateEdge).Run(txn)
+	require.NoError(t, err)
+	require.Equal(t, map[string]int{uid1: 3}, edges)
+
+	groups, err := ndgo.Query{}.GroupBy(fx, "", predicateName).Run(txn)
+	require.NoError(t, err)
+	require.Equal(t, map[string]int{firstName: 1, secondName: 1, thirdName: 2}, groups)
+
+	max, err := ndgo.Query{}.Max(fx, "", predicateAttr).RunString(txn)
+	require.NoError(t, err)
+	require.Equal(t, thirdAttr, max)
+	_, err = ndgo.Query{}.Max(fx, "", predicateAttr).Run(txn)
+	require.Error(t, err, "not a number")
+}
@@ -0,0 +1,557 @@
+package ndgo
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/dgraph-io/dgo/v210"
+	"github.com/dgraph-io/dgo/v210/protos/api"
+	log "github.com/ppp225/lvlog"
+)
+
+// --------------------------------------- iterator ---------------------------------------
+
+// BulkIterator yields items for BulkSet. Next returns io.EOF, when there are no more items.
+// Items can be SetRDF or string (N-Quads), SetJSON, []byte or json.RawMessage (JSON objects) or anything else, which is marshalled to JSON.
+type BulkIterator interface {
+	Next() (interface{}, error)
+}
+
+type sliceIterator struct {
+	items []interface{}
+	i     int
+}
+
+// NewSliceIterator creates BulkIterator over given items
+func NewSliceIterator(items ...interface{}) BulkIterator {
+	return &sliceIterator{items: items}
+}
+
+func (v *sliceIterator) Next() (interface{}, error) {
+	if v.i >= len(v.items) {
+		return nil, io.EOF
+	}
+	v.i++
+	return v.items[v.i-1], nil
+}
+
+type rdfIterator struct {
+	scanner *bufio.Scanner
+}
+
+// NewRDFIterator creates BulkIterator reading N-Quads line by line. Empty lines and comments are skipped.
+func NewRDFIterator(r io.Reader) BulkIterator {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	return &rdfIterator{scanner: scanner}
+}
+
+func (v *rdfIterator) Next() (interface{}, error) {
+	for v.scanner.Scan() {
+		line := bytes.TrimSpace(v.scanner.Bytes())
+		if len(line) == 0 || line[0] == '#' {
+			continue
+		}
+		return SetRDF(line), nil
+	}
+	if err := v.scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, io.EOF
+}
+
+type jsonIterator struct {
+	r       *bufio.Reader
+	dec     *json.Decoder
+	inArray bool
+}
+
+// NewJSONIterator creates BulkIterator reading JSON objects one by one.
+// Input can be a top level array of objects, or a stream of objects (i.e. one per line).
+func NewJSONIterator(r io.Reader) BulkIterator {
+	return &jsonIterator{r: bufio.NewReader(r)}
+}
+
+func (v *jsonIterator) Next() (interface{}, error) {
+	if v.dec == nil {
+		if err := v.start(); err != nil {
+			return nil, err
+		}
+	}
+	if !v.dec.More() {
+		if !v.inArray {
+			return nil, io.EOF
+		}
+		if _, err := v.dec.Token(); err != nil { // closing bracket
+			return nil, err
+		}
+		v.inArray = false
+		if !v.dec.More() {
+			return nil, io.EOF
+		}
+	}
+	var obj json.RawMessage
+	if err := v.dec.Decode(&obj); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+// start detects, if stream begins with an array, and if so, enters it
+func (v *jsonIterator) start() error {
+	v.dec = json.NewDecoder(v.r)
+	for {
+		c, err := v.r.ReadByte()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if isRDFSpace(c) {
+			continue
+		}
+		if err := v.r.UnreadByte(); err != nil {
+			return err
+		}
+		if c == '[' {
+			v.inArray = true
+			_, err := v.dec.Token()
+			return err
+		}
+		return nil
+	}
+}
+
+// --------------------------------------- options and stats ---------------------------------------
+
+// BulkOptions configures BulkSet. Zero values are replaced with defaults.
+type BulkOptions struct {
+	BatchSize   int // items per mutation, default 1000
+	Concurrency int // concurrent transactions, default 4
+	MaxRetries  int // retries of aborted batches, default 10
+	// UIDs seeds blank node -> uid mapping (without `_:` prefix), i.e. from previous BulkSet runs
+	UIDs map[string]string
+	// Progress is called after each committed batch. Calls are serialized.
+	Progress func(BulkStats)
+}
+
+// BulkStats reports BulkSet progress
+type BulkStats struct {
+	Items   int64
+	Batches int64
+	Retries int64
+	Elapsed time.Duration
+	// DatabaseTime and NetworkTime sum diagnostics of all batch transactions, in ms
+	DatabaseTime float64
+	NetworkTime  float64
+}
+
+// ItemsPerSecond returns throughput
+func (v BulkStats) ItemsPerSecond() float64 {
+	if v.Elapsed <= 0 {
+		return 0
+	}
+	return float64(v.Items) / v.Elapsed.Seconds()
+}
+
+// BulkResult is returned by BulkSet
+type BulkResult struct {
+	BulkStats
+	// UIDs maps all blank nodes (without `_:` prefix) to created uids, including seeded ones
+	UIDs map[string]string
+}
+
+// --------------------------------------- bulk set ---------------------------------------
+
+// BulkSet streams items from iterator, batches them into mutations and runs them in concurrent transactions using CommitNow.
+// Aborted batches are retried. Blank nodes keep their identity across batches:
+// a batch referencing a blank node created by another batch waits for it and uses the assigned uid.
+func BulkSet(ctx context.Context, dg *dgo.Dgraph, it BulkIterator, opts BulkOptions) (BulkResult, error) {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 1000
+	}
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = 4
+	}
+	if opts.MaxRetries <= 0 {
+		opts.MaxRetries = 10
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	b := &bulkLoader{
+		dg:      dg,
+		opts:    opts,
+		start:   time.Now(),
+		uids:    make(map[string]string, len(opts.UIDs)),
+		pending: make(map[string]*bulkBatch),
+	}
+	for k, v := range opts.UIDs {
+		b.uids[k] = v
+	}
+
+	batches := make(chan *bulkBatch, opts.Concurrency)
+	var wg sync.WaitGroup
+	for i := 0; i < opts.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for batch := range batches {
+				if err := b.run(ctx, batch); err != nil {
+					b.fail(err)
+					cancel()
+				}
+				close(batch.done)
+			}
+		}()
+	}
+
+	err := b.dispatch(ctx, it, batches)
+	close(batches)
+	wg.Wait()
+	if err != nil {
+		b.fail(err)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.stats.Elapsed = time.Since(b.start)
+	return BulkResult{BulkStats: b.stats, UIDs: b.uids}, b.err
+}
+
+type bulkLoader struct {
+	dg    *dgo.Dgraph
+	opts  BulkOptions
+	start time.Time
+
+	mu      sync.Mutex
+	uids    map[string]string     // resolved blank nodes
+	pending map[string]*bulkBatch // blank nodes being created by a batch
+	stats   BulkStats
+	err     error
+}
+
+type bulkBatch struct {
+	items  []bulkItem
+	owns   []string     // blank nodes created by this batch
+	deps   []*bulkBatch // batches creating blank nodes referenced by this batch
+	done   chan struct{}
+	failed bool
+}
+
+type bulkItem struct {
+	rdf    []byte
+	json   []byte
+	parsed interface{} // decoded json, if it references blank nodes
+	blanks []string
+}
+
+func (v *bulkLoader) fail(err error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.err == nil {
+		v.err = err
+	}
+}
+
+// dispatch reads iterator and sends batches to workers
+func (v *bulkLoader) dispatch(ctx context.Context, it BulkIterator, batches chan<- *bulkBatch) error {
+	batch := &bulkBatch{done: make(chan struct{})}
+	send := func() error {
+		if len(batch.items) == 0 {
+			return nil
+		}
+		select {
+		case batches <- batch:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		batch = &bulkBatch{done: make(chan struct{})}
+		return nil
+	}
+	for {
+		raw, err := it.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		item, err := newBulkItem(raw)
+		if err != nil {
+			return err
+		}
+		v.track(batch, item.blanks)
+		batch.items = append(batch.items, item)
+		if len(batch.items) >= v.opts.BatchSize {
+			if err := send(); err != nil {
+				return err
+			}
+		}
+	}
+	return send()
+}
+
+// track registers blank nodes of an item: either batch will create them, or depends on batch which does
+func (v *bulkLoader) track(batch *bulkBatch, blanks []string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	for _, name := range blanks {
+		if _, ok := v.uids[name]; ok {
+			continue
+		}
+		owner, ok := v.pending[name]
+		switch {
+		case !ok:
+			v.pending[name] = batch
+			batch.owns = append(batch.owns, name)
+		case owner != batch:
+			batch.deps = append(batch.deps, owner)
+		}
+	}
+}
+
+// run waits for dependencies, rewrites known blank nodes and commits the batch, retrying on abort
+func (v *bulkLoader) run(ctx context.Context, batch *bulkBatch) error {
+	for _, dep := range batch.deps {
+		select {
+		case <-dep.done:
+			if dep.failed {
+				batch.failed = true
+				return fmt.Errorf("ndgo: BulkSet batch depends on a failed batch")
+			}
+		case <-ctx.Done():
+			batch.failed = true
+			return ctx.Err()
+		}
+	}
+
+	mu, err := v.mutation(batch)
+	if err != nil {
+		batch.failed = true
+		return err
+	}
+	var retries int64
+	for {
+		txn := NewTxn(ctx, v.dg.NewTxn())
+		resp, err := txn.Mutate(mu)
+		txn.Discard()
+		if errors.Is(err, dgo.ErrAborted) && retries < int64(v.opts.MaxRetries) {
+			retries++
+			log.Tracef("BulkSet: batch aborted, retry %d\n", retries)
+			time.Sleep(time.Duration(retries) * 10 * time.Millisecond)
+			continue
+		}
+		if err != nil {
+			batch.failed = true
+			return err
+		}
+		v.commit(batch, resp, txn, retries)
+		return nil
+	}
+}
+
+// commit records created uids and updates stats
+func (v *bulkLoader) commit(batch *bulkBatch, resp *api.Response, txn *Txn, retries int64) {
+	v.mu.Lock()
+	for _, name := range batch.owns {
+		if uid, ok := resp.Uids[name]; ok {
+			v.uids[name] = uid
+		}
+		delete(v.pending, name)
+	}
+	v.stats.Items += int64(len(batch.items))
+	v.stats.Batches++
+	v.stats.Retries += retries
+	v.stats.DatabaseTime += txn.GetDatabaseTime()
+	v.stats.NetworkTime += txn.GetNetworkTime()
+	v.stats.Elapsed = time.Since(v.start)
+	stats := v.stats
+	if v.opts.Progress != nil {
+		v.opts.Progress(stats)
+	}
+	v.mu.Unlock()
+}
+
+// mutation builds a single mutation from batch items, replacing already created blank nodes with uids
+func (v *bulkLoader) mutation(batch *bulkBatch) (*api.Mutation, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	var rdf bytes.Buffer
+	var jsons [][]byte
+	for _, item := range batch.items {
+		switch {
+		case item.rdf != nil:
+			rdf.Write(replaceBlankNodesRDF(item.rdf, v.uids))
+			rdf.WriteByte('\n')
+		case item.parsed != nil:
+			jsonBytes, err := json.Marshal(replaceBlankNodesJSON(item.parsed, v.uids))
+			if err != nil {
+				return nil, err
+			}
+			if unwrapped := unwrapJSONArray(jsonBytes); len(unwrapped) > 0 {
+				jsons = append(jsons, unwrapped)
+			}
+		default:
+			if unwrapped := unwrapJSONArray(item.json); len(unwrapped) > 0 {
+				jsons = append(jsons, unwrapped)
+			}
+		}
+	}
+	mu := &api.Mutation{CommitNow: true}
+	if rdf.Len() > 0 {
+		mu.SetNquads = rdf.Bytes()
+	}
+	if len(jsons) > 0 {
+		mu.SetJson = byteJoinByCommaAndPutInBrackets(jsons...)
+	}
+	return mu, nil
+}
+
+func newBulkItem(raw interface{}) (item bulkItem, err error) {
+	switch val := raw.(type) {
+	case SetRDF:
+		item.rdf = []byte(val)
+	case string:
+		item.rdf = []byte(val)
+	case SetJSON:
+		item.json = []byte(val)
+	case json.RawMessage:
+		item.json = val
+	case []byte:
+		item.json = val
+	default:
+		if item.json, err = Marshal(raw); err != nil {
+			return item, err
+		}
+	}
+	if item.rdf != nil {
+		item.blanks = blankNodesRDF(item.rdf)
+		return item, nil
+	}
+	if !bytes.Contains(item.json, []byte(`"_:`)) {
+		return item, nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(item.json))
+	dec.UseNumber()
+	if err = dec.Decode(&item.parsed); err != nil {
+		return item, err
+	}
+	item.blanks = blankNodesJSON(item.parsed, nil)
+	return item, nil
+}
+
+// --------------------------------------- blank nodes ---------------------------------------
+
+// scanBlankNodesRDF calls fx for every `_:name` outside of string literals, with its start and end offsets
+func scanBlankNodesRDF(rdf []byte, fx func(start, end int)) {
+	for i := 0; i < len(rdf); i++ {
+		switch rdf[i] {
+		case '"': // skip literal
+			for i++; i < len(rdf) && rdf[i] != '"'; i++ {
+				if rdf[i] == '\\' {
+					i++
+				}
+			}
+		case '_':
+			if i+1 < len(rdf) && rdf[i+1] == ':' && (i == 0 || isRDFSpace(rdf[i-1])) {
+				end := i + 2
+				for end < len(rdf) {
+					var next byte
+					if end+1 < len(rdf) {
+						next = rdf[end+1]
+					}
+					if isBlankNodeEnd(rdf[end], next) {
+						break
+					}
+					end++
+				}
+				fx(i, end)
+				i = end
+			}
+		}
+	}
+}
+
+func blankNodesRDF(rdf []byte) (names []string) {
+	scanBlankNodesRDF(rdf, func(start, end int) {
+		names = append(names, string(rdf[start+2:end]))
+	})
+	return names
+}
+
+func replaceBlankNodesRDF(rdf []byte, uids map[string]string) []byte {
+	var res []byte
+	last := 0
+	scanBlankNodesRDF(rdf, func(start, end int) {
+		uid, ok := uids[string(rdf[start+2:end])]
+		if !ok {
+			return
+		}
+		res = append(res, rdf[last:start]...)
+		res = append(res, '<')
+		res = append(res, uid...)
+		res = append(res, '>')
+		last = end
+	})
+	if res == nil {
+		return rdf
+	}
+	return append(res, rdf[last:]...)
+}
+
+func blankNodesJSON(val interface{}, names []string) []string {
+	switch v := val.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			if s, ok := child.(string); ok && key == "uid" && len(s) > 2 && s[:2] == "_:" {
+				names = append(names, s[2:])
+				continue
+			}
+			names = blankNodesJSON(child, names)
+		}
+	case []interface{}:
+		for _, child := range v {
+			names = blankNodesJSON(child, names)
+		}
+	}
+	return names
+}
+
+// replaceBlankNodesJSON replaces known blank nodes in uid fields. It modifies val in place.
+func replaceBlankNodesJSON(val interface{}, uids map[string]string) interface{} {
+	switch v := val.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			if s, ok := child.(string); ok && key == "uid" && len(s) > 2 && s[:2] == "_:" {
+				if uid, ok := uids[s[2:]]; ok {
+					v[key] = uid
+				}
+				continue
+			}
+			replaceBlankNodesJSON(child, uids)
+		}
+	case []interface{}:
+		for _, child := range v {
+			replaceBlankNodesJSON(child, uids)
+		}
+	}
+	return val
+}
+
+// unwrapJSONArray strips brackets of a json array, so it can be joined with other objects
+func unwrapJSONArray(b []byte) []byte {
+	trimmed := bytes.TrimSpace(b)
+	if len(trimmed) >= 2 && trimmed[0] == '[' && trimmed[len(trimmed)-1] == ']' {
+		return trimmed[1 : len(trimmed)-1]
+	}
+	return trimmed
+}
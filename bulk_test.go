@@ -0,0 +1,122 @@
+package ndgo_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/ppp225/ndgo/v5"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBulkSet(t *testing.T) {
+	dg := dgNewClient()
+	defer setupTeardown(dg)()
+
+	// every node points to the previous one, so blank nodes are shared across batches
+	const nodes = 250
+	items := make([]interface{}, 0, nodes)
+	for i := 0; i < nodes; i++ {
+		if i%2 == 0 {
+			nq := setNodeRDF(fmt.Sprintf("n%d", i), fmt.Sprintf("bulk%d", i), firstAttr)
+			if i > 0 {
+				nq += setEdgeRDF(fmt.Sprintf("_:n%d", i), fmt.Sprintf("_:n%d", i-1))
+			}
+			items = append(items, nq)
+			continue
+		}
+		items = append(items, testStruct{
+			UID:  fmt.Sprintf("_:n%d", i),
+			Type: testType,
+			Name: fmt.Sprintf("bulk%d", i),
+			Attr: secondAttr,
+			Edge: &testStruct{UID: fmt.Sprintf("_:n%d", i-1)},
+		})
+	}
+
+	var progressCalls int
+	res, err := ndgo.BulkSet(context.Background(), dg, ndgo.NewSliceIterator(items...), ndgo.BulkOptions{
+		BatchSize:   20,
+		Concurrency: 4,
+		Progress:    func(ndgo.BulkStats) { progressCalls++ },
+	})
+	require.NoError(t, err)
+	require.EqualValues(t, nodes, res.Items)
+	require.EqualValues(t, 13, res.Batches)
+	require.Equal(t, 13, progressCalls)
+	require.Len(t, res.UIDs, nodes)
+	require.NotZero(t, res.ItemsPerSecond())
+
+	txn := ndgo.NewTxn(context.Background(), dg.NewReadOnlyTxn())
+	defer txn.Discard()
+	resp, err := ndgo.QueryDQL(fmt.Sprintf(`
+	{
+	  q(func: type(%s)) @filter(has(%s)) {
+	    count(uid)
+	  }
+	  e(func: uid(%s)) {
+	    %s { uid }
+	  }
+	}
+	`, testType, predicateName, res.UIDs["n100"], predicateEdge)).Run(txn)
+	require.NoError(t, err)
+	var decode struct {
+		Q []struct {
+			Count int `json:"count"`
+		} `json:"q"`
+		E []struct {
+			Edge []struct {
+				UID string `json:"uid"`
+			} `json:"testEdge"`
+		} `json:"e"`
+	}
+	require.NoError(t, json.Unmarshal(resp.GetJson(), &decode))
+	require.Equal(t, nodes, decode.Q[0].Count, "each blank node should be created only once")
+	require.Equal(t, res.UIDs["n99"], decode.E[0].Edge[0].UID, "edges across batches should point to the same node")
+}
+
+func TestBulkSetCompactRDF(t *testing.T) {
+	dg := dgNewClient()
+	defer setupTeardown(dg)()
+
+	// blank node names end at final `.` and at facets, even without space before them
+	items := []interface{}{
+		ndgo.SetRDF(`_:a <testName> "a" .`),
+		ndgo.SetRDF("_:b <testEdge> _:a(weight=1) .\n_:b <testName> \"b\" .\n_:c <testEdge> _:b."),
+	}
+	res, err := ndgo.BulkSet(context.Background(), dg, ndgo.NewSliceIterator(items...), ndgo.BulkOptions{BatchSize: 1})
+	require.NoError(t, err)
+	require.Len(t, res.UIDs, 3)
+	for _, name := range []string{"a", "b", "c"} {
+		require.Contains(t, res.UIDs, name)
+	}
+}
+
+func TestBulkIterators(t *testing.T) {
+	readAll := func(it ndgo.BulkIterator) (items []string) {
+		for {
+			item, err := it.Next()
+			if err == io.EOF {
+				return items
+			}
+			require.NoError(t, err)
+			switch v := item.(type) {
+			case ndgo.SetRDF:
+				items = append(items, string(v))
+			case json.RawMessage:
+				items = append(items, string(v))
+			}
+		}
+	}
+
+	require.Equal(t, []string{`_:a <name> "a" .`, `_:a <age> "1" .`},
+		readAll(ndgo.NewRDFIterator(strings.NewReader("# comment\n_:a <name> \"a\" .\n\n  _:a <age> \"1\" .  \n"))))
+	require.Equal(t, []string{`{"a":1}`, `{"b":[2]}`},
+		readAll(ndgo.NewJSONIterator(strings.NewReader(` [ {"a":1}, {"b":[2]} ] `))))
+	require.Equal(t, []string{`{"a":1}`, `{"b":[2]}`},
+		readAll(ndgo.NewJSONIterator(strings.NewReader("{\"a\":1}\n{\"b\":[2]}\n"))))
+	require.Len(t, readAll(ndgo.NewJSONIterator(strings.NewReader(""))), 0)
+}
@@ -0,0 +1,367 @@
+package ndgo
+
+import (
+	"container/list"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dgraph-io/dgo/v210/protos/api"
+)
+
+// --------------------------------------- cache ---------------------------------------
+
+// QueryCache is an in-process read-through LRU cache of JSON query responses, shared between Txns.
+// Entries are keyed by query text and vars, and expire after TTL. When a Txn using the cache commits,
+// entries of queries which read any of the mutated predicates are invalidated.
+// Only this process' commits are seen, so TTL bounds staleness of writes made elsewhere.
+// Safe for concurrent use.
+type QueryCache struct {
+	opts QueryCacheOptions
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List // front is most recently used
+	bytes   int
+	stats   QueryCacheStats
+	// gen is incremented on every invalidation. predGen and allGen record the last invalidation
+	// of a predicate or of everything, so responses read before an invalidation are not stored.
+	gen     uint64
+	predGen map[string]uint64
+	allGen  uint64
+}
+
+// QueryCacheOptions configures QueryCache. Zero values are replaced with defaults.
+type QueryCacheOptions struct {
+	TTL        time.Duration // entry lifetime, default 1 minute
+	MaxEntries int           // default 1000
+	MaxBytes   int           // total size of cached responses, default 64 MiB
+}
+
+// QueryCacheStats are cumulative stats of a QueryCache
+type QueryCacheStats struct {
+	Hits          int64
+	Misses        int64
+	Evictions     int64 // entries removed because of size limits or expiry
+	Invalidations int64 // entries removed because of mutated predicates
+	Entries       int
+	Bytes         int
+}
+
+type cacheEntry struct {
+	key     string
+	json    []byte
+	readTs  uint64
+	expires time.Time
+	// preds are predicates the query reads; all is set when they can't be determined, i.e. expand(_all_)
+	preds map[string]bool
+	all   bool
+}
+
+// NewQueryCache creates empty QueryCache
+func NewQueryCache(opts QueryCacheOptions) *QueryCache {
+	if opts.TTL <= 0 {
+		opts.TTL = time.Minute
+	}
+	if opts.MaxEntries <= 0 {
+		opts.MaxEntries = 1000
+	}
+	if opts.MaxBytes <= 0 {
+		opts.MaxBytes = 64 << 20
+	}
+	return &QueryCache{
+		opts:    opts,
+		entries: map[string]*list.Element{},
+		lru:     list.New(),
+		predGen: map[string]uint64{},
+	}
+}
+
+// Get returns cached response json of query with vars
+func (v *QueryCache) Get(q string, vars map[string]string) ([]byte, bool) {
+	e := v.get(cacheKey(q, vars))
+	if e == nil {
+		return nil, false
+	}
+	return e.json, true
+}
+
+// Set stores response json of query with vars
+func (v *QueryCache) Set(q string, vars map[string]string, json []byte) {
+	v.put(q, vars, json, 0, v.generation())
+}
+
+// Invalidate removes entries of queries reading any of preds. Call it after committing mutations
+// made without the cache, i.e. by BulkSet or other processes. `*` invalidates all entries.
+func (v *QueryCache) Invalidate(preds ...string) {
+	for _, p := range preds {
+		if p == "*" {
+			v.Purge()
+			return
+		}
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.gen++
+	for _, p := range preds {
+		v.predGen[p] = v.gen
+	}
+	for el := v.lru.Front(); el != nil; {
+		next := el.Next()
+		e := el.Value.(*cacheEntry)
+		if e.reads(preds) {
+			v.remove(el)
+			v.stats.Invalidations++
+		}
+		el = next
+	}
+}
+
+// Purge removes all entries
+func (v *QueryCache) Purge() {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.gen++
+	v.allGen = v.gen
+	v.stats.Invalidations += int64(v.lru.Len())
+	v.entries = map[string]*list.Element{}
+	v.lru.Init()
+	v.bytes = 0
+}
+
+// Stats returns a copy of cache stats
+func (v *QueryCache) Stats() QueryCacheStats {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	s := v.stats
+	s.Entries, s.Bytes = v.lru.Len(), v.bytes
+	return s
+}
+
+func (v *QueryCache) generation() uint64 {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.gen
+}
+
+func (v *QueryCache) get(key string) *cacheEntry {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	el, ok := v.entries[key]
+	if !ok {
+		v.stats.Misses++
+		return nil
+	}
+	e := el.Value.(*cacheEntry)
+	if time.Now().After(e.expires) {
+		v.remove(el)
+		v.stats.Evictions++
+		v.stats.Misses++
+		return nil
+	}
+	v.lru.MoveToFront(el)
+	v.stats.Hits++
+	return e
+}
+
+// put stores response read at generation gen, unless predicates it reads were invalidated since
+func (v *QueryCache) put(q string, vars map[string]string, json []byte, readTs, gen uint64) {
+	e := &cacheEntry{
+		key:     cacheKey(q, vars),
+		json:    json,
+		readTs:  readTs,
+		expires: time.Now().Add(v.opts.TTL),
+	}
+	e.preds, e.all = queryPredicates(q)
+	if e.size() > v.opts.MaxBytes {
+		return
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.allGen > gen {
+		return
+	}
+	for p, pg := range v.predGen {
+		if pg > gen && (e.all || e.preds[p]) {
+			return
+		}
+	}
+	if el, ok := v.entries[e.key]; ok {
+		v.remove(el)
+	}
+	v.entries[e.key] = v.lru.PushFront(e)
+	v.bytes += e.size()
+	for v.lru.Len() > v.opts.MaxEntries || v.bytes > v.opts.MaxBytes {
+		v.remove(v.lru.Back())
+		v.stats.Evictions++
+	}
+}
+
+func (v *QueryCache) remove(el *list.Element) {
+	e := v.lru.Remove(el).(*cacheEntry)
+	delete(v.entries, e.key)
+	v.bytes -= e.size()
+}
+
+func (v *cacheEntry) size() int {
+	return len(v.key) + len(v.json)
+}
+
+// reads reports whether query of the entry reads any of preds
+func (v *cacheEntry) reads(preds []string) bool {
+	for _, p := range preds {
+		if v.all || v.preds[p] {
+			return true
+		}
+	}
+	return false
+}
+
+func cacheKey(q string, vars map[string]string) string {
+	keys := make([]string, 0, len(vars))
+	for k := range vars {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	b.WriteString(q)
+	for _, k := range keys {
+		b.WriteByte(0)
+		b.WriteString(k)
+		b.WriteByte('=')
+		b.WriteString(vars[k])
+	}
+	return b.String()
+}
+
+// queryPredicates returns predicates read by query. all is set, if query expands predicates or can't be parsed.
+func queryPredicates(q string) (preds map[string]bool, all bool) {
+	doc, err := ParseDQL(q)
+	if err != nil {
+		return nil, true
+	}
+	preds = map[string]bool{}
+	for _, p := range doc.Predicates() {
+		preds[p] = true
+	}
+	var fromExpr func(e *DQLExpr)
+	fromExpr = func(e *DQLExpr) {
+		if e == nil {
+			return
+		}
+		switch e.Func {
+		case "expand":
+			all = true
+		case "type":
+			preds["dgraph.type"] = true
+		}
+		for _, a := range e.Args {
+			fromExpr(a)
+		}
+	}
+	doc.walkExprs(fromExpr)
+	return preds, all
+}
+
+// mutationPredicates returns predicates written by mutation, or `*` if they can't be determined
+func mutationPredicates(mu *api.Mutation) (preds []string) {
+	var nquads []NQuad
+	for i, json := range [][]byte{mu.SetJson, mu.DeleteJson} {
+		if len(json) == 0 {
+			continue
+		}
+		nqs, err := jsonToNQuads(json, i == 1)
+		if err != nil {
+			return []string{"*"}
+		}
+		nquads = append(nquads, nqs...)
+	}
+	for _, rdf := range [][]byte{mu.SetNquads, mu.DelNquads} {
+		if len(rdf) == 0 {
+			continue
+		}
+		nqs, err := ParseNQuads(string(rdf))
+		if err != nil {
+			return []string{"*"}
+		}
+		nquads = append(nquads, nqs...)
+	}
+	for _, nq := range nquads {
+		preds = append(preds, nq.Predicate)
+	}
+	return preds
+}
+
+// --------------------------------------- txn ---------------------------------------
+
+// WithCache makes Query and QueryWithVars of this Txn read through cache. Use it with read-only Txns:
+// once the Txn mutates, its queries bypass the cache, as they would see uncommitted writes.
+// Mutated predicates are invalidated on Commit, or immediately when using CommitNow.
+// Cache hits don't add to database or network time, and are counted by GetCacheHits.
+func (v *Txn) WithCache(cache *QueryCache) *Txn {
+	v.cache = cache
+	return v
+}
+
+// GetCacheHits gets number of queries served from cache
+func (v *Txn) GetCacheHits() int {
+	return v.diag.hits
+}
+
+// cachedQuery returns response from cache, or nil. gen is the cache generation to store the queried response at.
+func (v *Txn) cachedQuery(q string, vars map[string]string) (resp *api.Response, gen uint64) {
+	if v.cache == nil || v.mutated != nil {
+		return nil, 0
+	}
+	gen = v.cache.generation()
+	e := v.cache.get(cacheKey(q, vars))
+	if e == nil {
+		return nil, gen
+	}
+	v.diag.hits++
+	return &api.Response{
+		Json:    e.json,
+		Txn:     &api.TxnContext{StartTs: e.readTs},
+		Latency: &api.Latency{},
+	}, gen
+}
+
+func (v *Txn) cacheResponse(q string, vars map[string]string, resp *api.Response, gen uint64) {
+	if v.cache == nil || v.mutated != nil {
+		return
+	}
+	v.cache.put(q, vars, resp.Json, resp.GetTxn().GetStartTs(), gen)
+}
+
+// trackMutations records predicates of successful mutations, and invalidates them right away on commitNow
+func (v *Txn) trackMutations(commitNow bool, mutations ...*api.Mutation) {
+	if v.cache == nil || len(mutations) == 0 {
+		return
+	}
+	if v.mutated == nil {
+		v.mutated = map[string]bool{}
+	}
+	for _, mu := range mutations {
+		for _, p := range mutationPredicates(mu) {
+			v.mutated[p] = true
+		}
+	}
+	if commitNow {
+		v.invalidateMutated()
+	}
+}
+
+func (v *Txn) invalidateMutated() {
+	if v.cache == nil || len(v.mutated) == 0 {
+		return
+	}
+	preds := make([]string, 0, len(v.mutated))
+	for p := range v.mutated {
+		preds = append(preds, p)
+	}
+	sort.Strings(preds)
+	v.cache.Invalidate(preds...)
+	v.mutated = nil
+}
@@ -0,0 +1,118 @@
+package ndgo_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ppp225/ndgo/v5"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryCacheInvalidate(t *testing.T) {
+	cache := ndgo.NewQueryCache(ndgo.QueryCacheOptions{})
+	queries := []string{
+		`{ q(func: eq(name, $name)) { uid age } }`,
+		`{ q(func: type(Person)) { uid } }`,
+		`{ q(func: uid(0x1)) { expand(_all_) } }`,
+		`{ q(func: has(friend)) { ~friend { uid } } }`,
+	}
+	fill := func() {
+		cache.Purge()
+		for _, q := range queries {
+			cache.Set(q, map[string]string{"$name": "x"}, []byte(`{"q":[]}`))
+		}
+	}
+	cached := func() (res []bool) {
+		for _, q := range queries {
+			_, ok := cache.Get(q, map[string]string{"$name": "x"})
+			res = append(res, ok)
+		}
+		return res
+	}
+
+	var testData = []struct {
+		preds []string
+		out   []bool
+	}{
+		{preds: []string{"age"}, out: []bool{false, true, false, true}},
+		{preds: []string{"dgraph.type"}, out: []bool{true, false, false, true}},
+		{preds: []string{"friend"}, out: []bool{true, true, false, false}},
+		{preds: []string{"other"}, out: []bool{true, true, false, true}},
+		{preds: []string{"*"}, out: []bool{false, false, false, false}},
+	}
+	for i, tt := range testData {
+		fill()
+		require.Equal(t, []bool{true, true, true, true}, cached(), "Test i=%d", i)
+		cache.Invalidate(tt.preds...)
+		require.Equal(t, tt.out, cached(), "Test i=%d", i)
+	}
+
+	_, ok := cache.Get(queries[0], map[string]string{"$name": "y"})
+	require.False(t, ok)
+}
+
+func TestQueryCacheLimits(t *testing.T) {
+	cache := ndgo.NewQueryCache(ndgo.QueryCacheOptions{MaxEntries: 2})
+	cache.Set("{ a(func: has(a)) { uid } }", nil, []byte(`{}`))
+	cache.Set("{ b(func: has(b)) { uid } }", nil, []byte(`{}`))
+	_, ok := cache.Get("{ a(func: has(a)) { uid } }", nil)
+	require.True(t, ok)
+	cache.Set("{ c(func: has(c)) { uid } }", nil, []byte(`{}`))
+	_, ok = cache.Get("{ b(func: has(b)) { uid } }", nil)
+	require.False(t, ok, "least recently used entry should be evicted")
+	s := cache.Stats()
+	require.Equal(t, 2, s.Entries)
+	require.Equal(t, int64(1), s.Evictions)
+
+	cache = ndgo.NewQueryCache(ndgo.QueryCacheOptions{MaxBytes: 64})
+	cache.Set("{ a(func: has(a)) { uid } }", nil, make([]byte, 64))
+	require.Equal(t, 0, cache.Stats().Entries, "entries larger than MaxBytes should not be stored")
+
+	cache = ndgo.NewQueryCache(ndgo.QueryCacheOptions{TTL: time.Millisecond})
+	cache.Set("{ a(func: has(a)) { uid } }", nil, []byte(`{}`))
+	time.Sleep(5 * time.Millisecond)
+	_, ok = cache.Get("{ a(func: has(a)) { uid } }", nil)
+	require.False(t, ok, "expired entry should not be returned")
+}
+
+func TestTxnWithCache(t *testing.T) {
+	cache := ndgo.NewQueryCache(ndgo.QueryCacheOptions{})
+	q := `{ q(func: eq(name, $name)) { uid } }`
+	vars := map[string]string{"$name": "x"}
+	cache.Set(q, vars, []byte(`{"q":[{"uid":"0x1"}]}`))
+
+	// dgo.Txn is not needed, as query is served from cache
+	txn := ndgo.NewTxnWithoutContext(nil).WithCache(cache)
+	resp, err := txn.QueryWithVars(q, vars)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"q":[{"uid":"0x1"}]}`, string(resp.Json))
+	require.Equal(t, 1, txn.GetCacheHits())
+	require.Zero(t, txn.GetNetworkTime())
+	require.Zero(t, txn.GetDatabaseTime())
+	require.Equal(t, int64(1), cache.Stats().Hits)
+}
+
+func TestTxnWithCacheInvalidate(t *testing.T) {
+	dg := dgNewClient()
+	defer setupTeardown(dg)()
+	cache := ndgo.NewQueryCache(ndgo.QueryCacheOptions{})
+	q := `{ q(func: has(` + predicateName + `)) { ` + predicateName + ` } }`
+
+	txn := ndgo.NewTxnWithoutContext(dg.NewReadOnlyTxn()).WithCache(cache)
+	_, err := txn.Query(q)
+	require.NoError(t, err)
+	_, err = txn.Query(q)
+	require.NoError(t, err)
+	require.Equal(t, 1, txn.GetCacheHits())
+
+	wtxn := ndgo.NewTxnWithoutContext(dg.NewTxn()).WithCache(cache)
+	defer wtxn.Discard()
+	populateDBSimple(wtxn, t)
+	require.NoError(t, wtxn.Commit())
+
+	txn = ndgo.NewTxnWithoutContext(dg.NewReadOnlyTxn()).WithCache(cache)
+	resp, err := txn.Query(q)
+	require.NoError(t, err)
+	require.Equal(t, 0, txn.GetCacheHits())
+	require.Contains(t, string(resp.Json), firstName)
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/ppp225/ndgo/v5"
+)
+
+// eqTokenizers lists tokenizers, which allow the eq function
+var eqTokenizers = []string{"exact", "hash", "int", "float", "bool", "datetime", "year", "month", "day", "hour"}
+
+// goTypes maps dgraph scalar types to go types
+var goTypes = map[string]string{
+	"default":  "string",
+	"string":   "string",
+	"password": "string",
+	"int":      "int64",
+	"float":    "float64",
+	"bool":     "bool",
+	"datetime": "*time.Time",
+	"geo":      "json.RawMessage",
+	"uid":      "Node", // replaced with name of generated node type
+}
+
+// generate renders go source for given schema
+func generate(s *ndgo.Schema, pkg string) ([]byte, error) {
+	var b bytes.Buffer
+	p := func(format string, args ...interface{}) { fmt.Fprintf(&b, format, args...) }
+
+	preds := make([]ndgo.PredicateSchema, 0, len(s.Predicates))
+	for _, pred := range s.Predicates {
+		if !strings.HasPrefix(pred.Predicate, "dgraph.") {
+			preds = append(preds, pred)
+		}
+	}
+	sort.Slice(preds, func(i, j int) bool { return preds[i].Predicate < preds[j].Predicate })
+	types := make([]ndgo.TypeSchema, 0, len(s.Types))
+	for _, t := range s.Types {
+		if !strings.HasPrefix(t.Name, "dgraph.") {
+			types = append(types, t)
+		}
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i].Name < types[j].Name })
+
+	// idents are generated package level identifiers, by what they were generated from
+	idents := map[string]string{}
+	declare := func(ident, from string) error {
+		if other, ok := idents[ident]; ok {
+			return fmt.Errorf("ndgo-gen: %s and %s both map to go identifier %s", other, from, ident)
+		}
+		idents[ident] = from
+		return nil
+	}
+	for _, t := range types {
+		if err := declare(goName(t.Name), "type "+t.Name); err != nil {
+			return nil, err
+		}
+	}
+	// node type is named Node, unless a schema type is
+	node := "Node"
+	for idents[node] != "" {
+		node += "Ref"
+	}
+
+	imports := map[string]bool{}
+	var body bytes.Buffer
+	bp := func(format string, args ...interface{}) { fmt.Fprintf(&body, format, args...) }
+
+	// predicate constants
+	if len(preds) > 0 {
+		bp("// Predicate names\nconst (\n")
+		for _, pred := range preds {
+			if err := declare(predConst(pred.Predicate), "predicate "+pred.Predicate); err != nil {
+				return nil, err
+			}
+			bp("\t%s = %q\n", predConst(pred.Predicate), pred.Predicate)
+		}
+		bp(")\n\n")
+	}
+	if len(types) > 0 {
+		bp("// Type names\nconst (\n")
+		for _, t := range types {
+			if err := declare("Type"+goName(t.Name), "type name "+t.Name); err != nil {
+				return nil, err
+			}
+			bp("\t%s = %q\n", "Type"+goName(t.Name), t.Name)
+		}
+		bp(")\n\n")
+		idents[node] = "node type"
+		bp("// %s is an edge to any node\n", node)
+		bp("type %s struct {\n\tUID string `json:\"uid,omitempty\"`\n\tType []string `json:\"dgraph.type,omitempty\"`\n}\n\n", node)
+	}
+
+	// type structs and query helpers
+	for _, t := range types {
+		name := goName(t.Name)
+		bp("// %s represents dgraph type %s\n", name, t.Name)
+		bp("type %s struct {\n", name)
+		bp("\tUID string `json:\"uid,omitempty\"`\n")
+		bp("\tType []string `json:\"dgraph.type,omitempty\"`\n")
+		fields := map[string]string{"UID": "uid", "Type": "dgraph.type"}
+		for _, f := range t.Fields {
+			if strings.HasPrefix(f.Name, "~") {
+				continue
+			}
+			pred := s.Predicate(f.Name)
+			if pred == nil {
+				return nil, fmt.Errorf("ndgo-gen: type %s field %s has no predicate in schema", t.Name, f.Name)
+			}
+			field := goName(f.Name)
+			if field == "UID" || field == "Type" {
+				field += "Pred" // i.e. predicate `type`
+			}
+			if other, ok := fields[field]; ok {
+				return nil, fmt.Errorf("ndgo-gen: type %s fields %s and %s both map to go field %s", t.Name, other, f.Name, field)
+			}
+			fields[field] = f.Name
+			typ, ok := goTypes[pred.Type]
+			if !ok {
+				return nil, fmt.Errorf("ndgo-gen: predicate %s has unsupported type %s", pred.Predicate, pred.Type)
+			}
+			if pred.Type == "uid" {
+				typ = node
+			}
+			switch {
+			case pred.List:
+				typ = "[]" + strings.TrimPrefix(typ, "*")
+			case pred.Type == "uid":
+				typ = "*" + typ
+			}
+			imports[`"time"`] = imports[`"time"`] || strings.Contains(typ, "time.")
+			imports[`"encoding/json"`] = imports[`"encoding/json"`] || strings.Contains(typ, "json.")
+			bp("\t%s %s `json:\"%s,omitempty\"`\n", field, typ, f.Name)
+		}
+		bp("}\n\n")
+
+		for _, f := range t.Fields {
+			pred := s.Predicate(f.Name)
+			if pred == nil || pred.List || !supportsEq(pred) {
+				continue
+			}
+			imports[`"github.com/ppp225/ndgo/v5"`] = true
+			fx := fmt.Sprintf("Get%sBy%s", name, goName(f.Name))
+			if err := declare(fx, "helper of type "+t.Name); err != nil {
+				return nil, err
+			}
+			argType := strings.TrimPrefix(goTypes[pred.Type], "*")
+			val := "fmt.Sprint(value)"
+			switch argType {
+			case "string":
+				val = "strconv.Quote(value)" // quoted, so value isn't interpreted as DQL
+				imports[`"strconv"`] = true
+			case "time.Time":
+				val = "value.Format(time.RFC3339Nano)"
+				imports[`"time"`] = true
+			default:
+				imports[`"fmt"`] = true
+			}
+			bp("// %s queries %s nodes by %s. Usage: resp, err := %s(value).Run(txn)\n", fx, t.Name, f.Name, fx)
+			bp("func %s(value %s) ndgo.QueryDQL {\n", fx, argType)
+			bp("\treturn ndgo.Query{}.GetPredExpandType(\"q\", \"eq\", %s, %s, \"\", \"\", \"uid dgraph.type\", %s)\n", predConst(f.Name), val, "Type"+name)
+			bp("}\n\n")
+		}
+	}
+
+	p("// Code generated by ndgo-gen. DO NOT EDIT.\n\n")
+	p("package %s\n\n", pkg)
+	var imps []string
+	for imp, used := range imports {
+		if used {
+			imps = append(imps, imp)
+		}
+	}
+	// std imports first, as their paths have no dots
+	sort.Slice(imps, func(i, j int) bool {
+		if std := !strings.Contains(imps[i], "."); std != !strings.Contains(imps[j], ".") {
+			return std
+		}
+		return imps[i] < imps[j]
+	})
+	if len(imps) > 0 {
+		p("import (\n")
+		for i, imp := range imps {
+			if i > 0 && !strings.Contains(imps[i-1], ".") && strings.Contains(imp, ".") {
+				p("\n") // separate std imports
+			}
+			p("\t%s\n", imp)
+		}
+		p(")\n\n")
+	}
+	b.Write(body.Bytes())
+	return format.Source(b.Bytes())
+}
+
+func supportsEq(pred *ndgo.PredicateSchema) bool {
+	if pred.Type == "uid" || !pred.Index {
+		return false
+	}
+	for _, tok := range eqTokenizers {
+		if pred.HasTokenizer(tok) {
+			return true
+		}
+	}
+	return false
+}
+
+func predConst(pred string) string {
+	return "Pred" + goName(pred)
+}
+
+// goName converts dgraph name to exported go identifier, i.e. `ndgo.migration.version` -> `NdgoMigrationVersion`
+func goName(name string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	res := b.String()
+	if res == "" || unicode.IsDigit(rune(res[0])) {
+		res = "X" + res
+	}
+	return res
+}
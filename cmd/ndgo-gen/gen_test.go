@@ -0,0 +1,126 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/ppp225/ndgo/v5"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGoName(t *testing.T) {
+	var testData = []struct {
+		in  string
+		out string
+	}{
+		{in: "testName", out: "TestName"},
+		{in: "ndgo.migration.version", out: "NdgoMigrationVersion"},
+		{in: "test_edge", out: "TestEdge"},
+		{in: "1st", out: "X1st"},
+	}
+	for i, tt := range testData {
+		require.Equal(t, tt.out, goName(tt.in), "Test i=%d", i)
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	s, err := ndgo.ParseSchema(`
+		<testName>: string @index(hash) @upsert .
+		<testAttribute>: string .
+		<testAge>: int @index(int) .
+		<testEdge>: [uid] .
+		<dgraph.type>: [string] @index(exact) .
+
+		type TestType {
+			testName
+			testAttribute
+			testAge
+			testEdge
+		}
+	`)
+	require.NoError(t, err)
+	src, err := generate(s, "model")
+	require.NoError(t, err)
+	out := string(src)
+	t.Log(out)
+
+	require.Contains(t, out, "package model")
+	require.Regexp(t, `PredTestName += "testName"`, out)
+	require.NotContains(t, out, "PredDgraphType")
+	require.Contains(t, out, "type TestType struct")
+	require.Regexp(t, "TestEdge +\\[\\]Node +`json:\"testEdge,omitempty\"`", out)
+	require.Contains(t, out, "func GetTestTypeByTestName(value string) ndgo.QueryDQL")
+	require.Contains(t, out, "func GetTestTypeByTestAge(value int64) ndgo.QueryDQL")
+	require.NotContains(t, out, "GetTestTypeByTestAttribute", "not indexed predicates should not have helpers")
+}
+
+func TestGenerateFields(t *testing.T) {
+	s, err := ndgo.ParseSchema(`
+		<type>: string .
+		<testAttribute>: string .
+
+		type TestType {
+			type
+			testAttribute
+		}
+	`)
+	require.NoError(t, err)
+	src, err := generate(s, "model")
+	require.NoError(t, err)
+	out := string(src)
+
+	require.NotContains(t, out, "github.com/ppp225/ndgo", "ndgo should be imported only by helpers")
+	require.Regexp(t, "TypePred +string +`json:\"type,omitempty\"`", out)
+
+	s, err = ndgo.ParseSchema(`
+		<testName>: string .
+
+		type TestType {
+			testName
+			testAttribute
+		}
+	`)
+	require.NoError(t, err)
+	_, err = generate(s, "model")
+	require.EqualError(t, err, "ndgo-gen: type TestType field testAttribute has no predicate in schema")
+
+	s, err = ndgo.ParseSchema(`
+		<type>: string .
+		<typePred>: string .
+
+		type TestType {
+			type
+			typePred
+		}
+	`)
+	require.NoError(t, err)
+	_, err = generate(s, "model")
+	require.EqualError(t, err, "ndgo-gen: type TestType fields type and typePred both map to go field TypePred")
+}
+
+func TestGenerateIdents(t *testing.T) {
+	s, err := ndgo.ParseSchema(`
+		<test_name>: string .
+		<testName>: string .
+	`)
+	require.NoError(t, err)
+	_, err = generate(s, "model")
+	require.EqualError(t, err, "ndgo-gen: predicate testName and predicate test_name both map to go identifier PredTestName")
+
+	s, err = ndgo.ParseSchema(`
+		<name>: string @index(exact) .
+		<parent>: uid .
+
+		type Node {
+			name
+			parent
+		}
+	`)
+	require.NoError(t, err)
+	src, err := generate(s, "model")
+	require.NoError(t, err)
+	out := string(src)
+	require.Contains(t, out, "type Node struct")
+	require.Contains(t, out, "type NodeRef struct", "node type should be renamed, when schema has type Node")
+	require.Regexp(t, "Parent +\\*NodeRef +`json:\"parent,omitempty\"`", out)
+	require.Contains(t, out, "ndgo.Query{}.GetPredExpandType(\"q\", \"eq\", PredName, strconv.Quote(value),", "string values should be quoted")
+}
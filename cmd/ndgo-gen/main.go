@@ -0,0 +1,84 @@
+// Command ndgo-gen generates predicate constants, type structs and typed query helpers from dgraph schema.
+//
+// Usage:
+//
+//	//go:generate go run github.com/ppp225/ndgo/v5/cmd/ndgo-gen -schema schema.dql -pkg model -out schema_gen.go
+//	//go:generate go run github.com/ppp225/ndgo/v5/cmd/ndgo-gen -addr localhost:9080 -pkg model -out schema_gen.go
+//
+// Schema file can be in the Alter format, or the JSON output of `schema {}` query.
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/dgraph-io/dgo/v210"
+	"github.com/dgraph-io/dgo/v210/protos/api"
+	"github.com/ppp225/ndgo/v5"
+	"google.golang.org/grpc"
+)
+
+func main() {
+	schemaFile := flag.String("schema", "", "schema file (Alter format or `schema {}` JSON output)")
+	addr := flag.String("addr", "", "dgraph alpha grpc address to read live schema from, i.e. localhost:9080")
+	pkg := flag.String("pkg", os.Getenv("GOPACKAGE"), "package name of generated file")
+	out := flag.String("out", "", "output file, stdout if empty")
+	flag.Parse()
+
+	if err := run(*schemaFile, *addr, *pkg, *out); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(schemaFile, addr, pkg, out string) error {
+	if pkg == "" {
+		return fmt.Errorf("ndgo-gen: -pkg is required")
+	}
+	s, err := loadSchema(schemaFile, addr)
+	if err != nil {
+		return err
+	}
+	src, err := generate(s, pkg)
+	if err != nil {
+		return err
+	}
+	if out == "" {
+		_, err = os.Stdout.Write(src)
+		return err
+	}
+	return ioutil.WriteFile(out, src, 0644)
+}
+
+func loadSchema(schemaFile, addr string) (*ndgo.Schema, error) {
+	switch {
+	case schemaFile != "":
+		data, err := ioutil.ReadFile(schemaFile)
+		if err != nil {
+			return nil, err
+		}
+		if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+			return ndgo.ParseSchemaJSON(trimmed)
+		}
+		return ndgo.ParseSchema(string(data))
+	case addr != "":
+		conn, err := grpc.Dial(addr, grpc.WithInsecure())
+		if err != nil {
+			return nil, err
+		}
+		defer conn.Close()
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		dg := dgo.NewDgraphClient(api.NewDgraphClient(conn))
+		txn := ndgo.NewTxn(ctx, dg.NewReadOnlyTxn())
+		defer txn.Discard()
+		return txn.Schema()
+	default:
+		return nil, fmt.Errorf("ndgo-gen: one of -schema or -addr is required")
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/ppp225/ndgo/v5"
+)
+
+func init() {
+	commands["fmt"] = command{
+		usage: "format DQL queries from files or stdin",
+		run:   runFmt,
+	}
+}
+
+func runFmt(args []string) error {
+	fs := flag.NewFlagSet("fmt", flag.ExitOnError)
+	write := fs.Bool("w", false, "write result to source file instead of stdout")
+	list := fs.Bool("l", false, "list files whose formatting differs")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: ndgo fmt [flags] [file]...")
+		fmt.Fprintln(fs.Output(), "Reads stdin, if no files are given.")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() == 0 {
+		if *write {
+			return fmt.Errorf("cannot use -w with stdin")
+		}
+		src, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			return err
+		}
+		return formatFile("<stdin>", src, os.Stdout, false, *list)
+	}
+	for _, file := range fs.Args() {
+		src, err := ioutil.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		if err := formatFile(file, src, os.Stdout, *write, *list); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// formatFile formats src and writes it to out, or back to file if write is set. With list, only names of changed files are written.
+// Files with comments aren't written, as formatting drops them.
+func formatFile(file string, src []byte, out io.Writer, write, list bool) error {
+	doc, err := ndgo.ParseDQL(string(src))
+	if err != nil {
+		return fmt.Errorf("%s: %w", file, err)
+	}
+	if write && doc.Comments > 0 {
+		return fmt.Errorf("%s: not written, as formatting would drop its comments", file)
+	}
+	formatted, err := ndgo.FormatDQL(string(src))
+	if err != nil {
+		return fmt.Errorf("%s: %w", file, err)
+	}
+	res := []byte(formatted + "\n")
+	changed := !bytes.Equal(src, res)
+	if list {
+		if changed {
+			fmt.Fprintln(out, file)
+		}
+		if !write {
+			return nil
+		}
+	}
+	if write {
+		if !changed {
+			return nil
+		}
+		return ioutil.WriteFile(file, res, 0644)
+	}
+	_, err = out.Write(res)
+	return err
+}
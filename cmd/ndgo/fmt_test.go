@@ -0,0 +1,48 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatFile(t *testing.T) {
+	src := []byte(`{q(func:has(name)){uid name}}`)
+	formatted := "{\n  q(func: has(name)) {\n    uid\n    name\n  }\n}\n"
+
+	var out bytes.Buffer
+	require.NoError(t, formatFile("a.dql", src, &out, false, false))
+	require.Equal(t, formatted, out.String())
+
+	out.Reset()
+	require.NoError(t, formatFile("a.dql", src, &out, false, true))
+	require.Equal(t, "a.dql\n", out.String())
+	out.Reset()
+	require.NoError(t, formatFile("a.dql", []byte(formatted), &out, false, true))
+	require.Empty(t, out.String())
+
+	dir, err := ioutil.TempDir("", "ndgo-fmt")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "a.dql")
+	require.NoError(t, ioutil.WriteFile(file, src, 0644))
+	require.NoError(t, formatFile(file, src, &out, true, false))
+	res, err := ioutil.ReadFile(file)
+	require.NoError(t, err)
+	require.Equal(t, formatted, string(res))
+
+	commented := []byte("# people\n{q(func:has(name)){uid name}}")
+	require.NoError(t, ioutil.WriteFile(file, commented, 0644))
+	err = formatFile(file, commented, &out, true, false)
+	require.EqualError(t, err, file+": not written, as formatting would drop its comments")
+	res, err = ioutil.ReadFile(file)
+	require.NoError(t, err)
+	require.Equal(t, commented, res)
+
+	err = formatFile("b.dql", []byte(`{q(func:has(name){uid}}`), &out, false, false)
+	require.EqualError(t, err, `b.dql: ndgo: dql 1:18: expected ',' or ')', got "{"`)
+}
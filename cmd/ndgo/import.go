@@ -0,0 +1,143 @@
+package main
+
+import (
+	"compress/gzip"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ppp225/ndgo/v5"
+)
+
+func init() {
+	commands["import"] = command{
+		usage: "load .rdf, .rdf.gz, .json or .json.gz files",
+		run:   runImport,
+	}
+}
+
+func runImport(args []string) error {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	var conn connFlags
+	conn.register(fs)
+	batch := fs.Int("batch", 1000, "N-Quads or JSON objects per mutation")
+	concurrency := fs.Int("conc", 4, "number of concurrent transactions")
+	quiet := fs.Bool("q", false, "don't print progress")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: ndgo import [flags] <file>...")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return fmt.Errorf("no files given")
+	}
+
+	it := &filesIterator{files: fs.Args(), verbose: !*quiet}
+	defer it.close()
+
+	ctx, dg, cleanup, err := conn.connect()
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	var lastPrint time.Time
+	res, err := ndgo.BulkSet(ctx, dg, it, ndgo.BulkOptions{
+		BatchSize:   *batch,
+		Concurrency: *concurrency,
+		Progress: func(s ndgo.BulkStats) {
+			if *quiet || time.Since(lastPrint) < time.Second {
+				return
+			}
+			lastPrint = time.Now()
+			printImportStats(s)
+		},
+	})
+	if !*quiet {
+		printImportStats(res.BulkStats)
+	}
+	return err
+}
+
+func printImportStats(s ndgo.BulkStats) {
+	fmt.Fprintf(os.Stderr, "[%s] %d items, %d batches, %d retries, %.0f items/s, db %.0fms, nw %.0fms\n",
+		s.Elapsed.Round(time.Second), s.Items, s.Batches, s.Retries, s.ItemsPerSecond(), s.DatabaseTime, s.NetworkTime)
+}
+
+// filesIterator chains iterators of all files, so blank nodes are shared between files
+type filesIterator struct {
+	files   []string
+	verbose bool
+	current string
+	it      ndgo.BulkIterator
+	closers []io.Closer // of current file
+}
+
+func (v *filesIterator) Next() (interface{}, error) {
+	for {
+		if v.it == nil {
+			if len(v.files) == 0 {
+				return nil, io.EOF
+			}
+			if err := v.open(v.files[0]); err != nil {
+				return nil, err
+			}
+			v.files = v.files[1:]
+		}
+		item, err := v.it.Next()
+		if err == io.EOF {
+			v.it = nil
+			v.close() // so files don't stay open until the import ends
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", v.current, err)
+		}
+		return item, nil
+	}
+}
+
+func (v *filesIterator) open(file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	v.closers = append(v.closers, f)
+	v.current = file
+	if v.verbose {
+		fmt.Fprintf(os.Stderr, "loading %s\n", file)
+	}
+
+	var r io.Reader = f
+	name := strings.ToLower(file)
+	if strings.HasSuffix(name, ".gz") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+		v.closers = append(v.closers, gz)
+		r = gz
+		name = strings.TrimSuffix(name, ".gz")
+	}
+	switch filepath.Ext(name) {
+	case ".rdf", ".nq", ".nquads":
+		v.it = ndgo.NewRDFIterator(r)
+	case ".json":
+		v.it = ndgo.NewJSONIterator(r)
+	default:
+		return fmt.Errorf("%s: unsupported file type, expected .rdf, .rdf.gz, .json or .json.gz", file)
+	}
+	return nil
+}
+
+func (v *filesIterator) close() {
+	for _, c := range v.closers {
+		c.Close()
+	}
+	v.closers = nil
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ppp225/ndgo/v5"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilesIterator(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ndgo-import")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	rdf := filepath.Join(dir, "a.rdf")
+	require.NoError(t, ioutil.WriteFile(rdf, []byte("# comment\n_:a <name> \"a\" .\n\n_:a <age> \"1\" .\n"), 0644))
+	jsonGz := filepath.Join(dir, "b.json.gz")
+	f, err := os.Create(jsonGz)
+	require.NoError(t, err)
+	gz := gzip.NewWriter(f)
+	_, err = gz.Write([]byte(`[{"uid":"_:a","name":"b"}, {"name":"c"}]`))
+	require.NoError(t, err)
+	require.NoError(t, gz.Close())
+	require.NoError(t, f.Close())
+
+	it := &filesIterator{files: []string{rdf, jsonGz}}
+	defer it.close()
+	var items []string
+	for {
+		item, err := it.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		switch v := item.(type) {
+		case json.RawMessage:
+			items = append(items, string(v))
+			require.Len(t, it.closers, 2, "only the current file and its gzip reader should be open")
+		case ndgo.SetRDF:
+			items = append(items, string(v))
+		}
+	}
+	require.Empty(t, it.closers, "files should be closed at EOF")
+	require.Equal(t, []string{`_:a <name> "a" .`, `_:a <age> "1" .`, `{"uid":"_:a","name":"b"}`, `{"name":"c"}`}, items)
+
+	it = &filesIterator{files: []string{filepath.Join(dir, "c.csv")}}
+	_, err = it.Next()
+	require.Error(t, err)
+}
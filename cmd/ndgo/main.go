@@ -0,0 +1,149 @@
+// Command ndgo is a command line tool for working with dgraph through ndgo.
+//
+// Usage:
+//
+//	ndgo <command> [flags] [args]
+//
+// Run `ndgo help` to list available commands.
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/dgraph-io/dgo/v210"
+	"github.com/dgraph-io/dgo/v210/protos/api"
+	"github.com/ppp225/ndgo/v5"
+	"google.golang.org/grpc"
+)
+
+// command is a single ndgo subcommand
+type command struct {
+	usage string
+	run   func(args []string) error
+}
+
+var commands = map[string]command{}
+
+func main() {
+	if len(os.Args) < 2 || os.Args[1] == "help" || os.Args[1] == "-h" || os.Args[1] == "--help" {
+		printUsage(os.Stdout)
+		return
+	}
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "ndgo: unknown command %q\n\n", os.Args[1])
+		printUsage(os.Stderr)
+		os.Exit(2)
+	}
+	if err := cmd.run(os.Args[2:]); err != nil {
+		fmt.Fprintf(os.Stderr, "ndgo %s: %v\n", os.Args[1], err)
+		os.Exit(1)
+	}
+}
+
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: ndgo <command> [flags] [args]")
+	fmt.Fprintln(w, "\nCommands:")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "  %-8s %s\n", name, commands[name].usage)
+	}
+	fmt.Fprintln(w, "\nRun `ndgo <command> -h` for command flags.")
+}
+
+// --------------------------------------- connection ---------------------------------------
+
+// connFlags are flags shared by commands which connect to dgraph
+type connFlags struct {
+	addr    string
+	timeout time.Duration
+}
+
+func (v *connFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&v.addr, "addr", "localhost:9080", "dgraph alpha grpc address")
+	fs.DurationVar(&v.timeout, "timeout", 0, "timeout of the whole command, 0 means no timeout")
+}
+
+// connect creates dgraph client and context. Call returned func to clean up.
+func (v *connFlags) connect() (context.Context, *dgo.Dgraph, func(), error) {
+	conn, err := grpc.Dial(v.addr, grpc.WithInsecure())
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	ctx, cancel := context.Background(), func() {}
+	if v.timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, v.timeout)
+	}
+	dg := dgo.NewDgraphClient(api.NewDgraphClient(conn))
+	return ctx, dg, func() {
+		cancel()
+		conn.Close()
+	}, nil
+}
+
+// --------------------------------------- input and output ---------------------------------------
+
+// outFlags are flags shared by commands which print responses
+type outFlags struct {
+	quiet   bool
+	compact bool
+}
+
+func (v *outFlags) register(fs *flag.FlagSet) {
+	fs.BoolVar(&v.quiet, "q", false, "don't print timings")
+	fs.BoolVar(&v.compact, "compact", false, "print json on a single line")
+}
+
+// printTimings prints database and network time collected by txn to stderr
+func (v *outFlags) printTimings(txn *ndgo.Txn) {
+	if !v.quiet {
+		fmt.Fprintf(os.Stderr, "db %.2fms, nw %.2fms\n", txn.GetDatabaseTime(), txn.GetNetworkTime())
+	}
+}
+
+// writeJSON writes data to w, indented unless compact, followed by a newline
+func writeJSON(w io.Writer, data []byte, compact bool) error {
+	var b bytes.Buffer
+	var err error
+	if compact {
+		err = json.Compact(&b, data)
+	} else {
+		err = json.Indent(&b, data, "", "  ")
+	}
+	if err != nil {
+		return err
+	}
+	b.WriteByte('\n')
+	_, err = w.Write(b.Bytes())
+	return err
+}
+
+// readInput reads file, or stdin if file is empty or `-`
+func readInput(file string) ([]byte, error) {
+	if file == "" || file == "-" {
+		return ioutil.ReadAll(os.Stdin)
+	}
+	return ioutil.ReadFile(file)
+}
+
+// inputArg returns the optional single file argument of fs
+func inputArg(fs *flag.FlagSet) (string, error) {
+	if fs.NArg() > 1 {
+		fs.Usage()
+		return "", fmt.Errorf("expected at most one file, got %d", fs.NArg())
+	}
+	return fs.Arg(0), nil
+}
@@ -0,0 +1,293 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/dgraph-io/dgo/v210/protos/api"
+	"github.com/ppp225/ndgo/v5"
+)
+
+func init() {
+	commands["query"] = command{
+		usage: "run DQL query from file or stdin",
+		run:   runQuery,
+	}
+	commands["mutate"] = command{
+		usage: "run N-Quads or JSON mutation from file or stdin",
+		run:   runMutate,
+	}
+	commands["upsert"] = command{
+		usage: "run query and conditional mutation in a single request",
+		run:   runUpsert,
+	}
+}
+
+// --------------------------------------- query ---------------------------------------
+
+func runQuery(args []string) error {
+	fs := flag.NewFlagSet("query", flag.ExitOnError)
+	var conn connFlags
+	conn.register(fs)
+	var out outFlags
+	out.register(fs)
+	vars := varsFlag{}
+	fs.Var(vars, "var", "query variable as name=value, can be repeated")
+	rdf := fs.Bool("rdf", false, "return response as N-Quads instead of json")
+	bestEffort := fs.Bool("be", false, "best effort read, which doesn't wait for latest timestamp")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: ndgo query [flags] [file]")
+		fmt.Fprintln(fs.Output(), "Reads stdin, if no file is given.")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	file, err := inputArg(fs)
+	if err != nil {
+		return err
+	}
+	q, err := readInput(file)
+	if err != nil {
+		return err
+	}
+
+	ctx, dg, cleanup, err := conn.connect()
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	dtxn := dg.NewReadOnlyTxn()
+	if *bestEffort {
+		dtxn = dtxn.BestEffort()
+	}
+	txn := ndgo.NewTxn(ctx, dtxn)
+	defer txn.Discard()
+
+	resp, err := query(txn, string(q), vars, *rdf)
+	if err != nil {
+		return err
+	}
+	if *rdf {
+		_, err = os.Stdout.Write(resp.GetRdf())
+	} else {
+		err = writeJSON(os.Stdout, resp.GetJson(), out.compact)
+	}
+	out.printTimings(txn)
+	return err
+}
+
+// query runs q with QueryWithVars, if there are any vars, as json or rdf
+func query(txn *ndgo.Txn, q string, vars map[string]string, rdf bool) (*api.Response, error) {
+	switch {
+	case rdf && len(vars) > 0:
+		return txn.QueryRDFWithVars(q, vars)
+	case rdf:
+		return txn.QueryRDF(q)
+	case len(vars) > 0:
+		return txn.QueryWithVars(q, vars)
+	}
+	return txn.Query(q)
+}
+
+// varsFlag collects repeated `-var name=value` flags into query vars. Names get `$` prefix, if missing.
+type varsFlag map[string]string
+
+func (v varsFlag) String() string {
+	keys := make([]string, 0, len(v))
+	for k := range v {
+		keys = append(keys, k+"="+v[k])
+	}
+	sort.Strings(keys)
+	return strings.Join(keys, ",")
+}
+
+func (v varsFlag) Set(s string) error {
+	idx := strings.IndexByte(s, '=')
+	if idx <= 0 {
+		return fmt.Errorf("expected name=value, got %q", s)
+	}
+	v["$"+strings.TrimPrefix(s[:idx], "$")] = s[idx+1:]
+	return nil
+}
+
+// --------------------------------------- mutate ---------------------------------------
+
+// mutationFlags are flags shared by mutate and upsert
+type mutationFlags struct {
+	delete bool
+	format string
+}
+
+func (v *mutationFlags) register(fs *flag.FlagSet) {
+	fs.BoolVar(&v.delete, "delete", false, "delete instead of set")
+	fs.StringVar(&v.format, "format", "auto", "mutation format: rdf, json or auto, which detects json by leading { or [")
+}
+
+func runMutate(args []string) error {
+	fs := flag.NewFlagSet("mutate", flag.ExitOnError)
+	var conn connFlags
+	conn.register(fs)
+	var out outFlags
+	out.register(fs)
+	var muf mutationFlags
+	muf.register(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: ndgo mutate [flags] [file]")
+		fmt.Fprintln(fs.Output(), "Reads stdin, if no file is given. The mutation is committed immediately.")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	file, err := inputArg(fs)
+	if err != nil {
+		return err
+	}
+	data, err := readInput(file)
+	if err != nil {
+		return err
+	}
+	mu, err := mutation(data, muf.format, muf.delete)
+	if err != nil {
+		return err
+	}
+	mu.CommitNow = true
+
+	ctx, dg, cleanup, err := conn.connect()
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	txn := ndgo.NewTxn(ctx, dg.NewTxn())
+	defer txn.Discard()
+
+	resp, err := txn.Mutate(mu)
+	if err != nil {
+		return err
+	}
+	err = writeMutationResult(os.Stdout, resp, out.compact)
+	out.printTimings(txn)
+	return err
+}
+
+// mutation builds mutation from N-Quads or json. N-Quads are validated locally, so errors point to line and column.
+func mutation(data []byte, format string, isDelete bool) (*api.Mutation, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return nil, fmt.Errorf("empty mutation")
+	}
+	if format == "auto" {
+		format = "rdf"
+		if data[0] == '{' || data[0] == '[' {
+			format = "json"
+		}
+	}
+	switch format {
+	case "json":
+		var val interface{}
+		if err := json.Unmarshal(data, &val); err != nil {
+			return nil, fmt.Errorf("invalid json mutation: %w", err)
+		}
+		if isDelete {
+			return &api.Mutation{DeleteJson: data}, nil
+		}
+		return &api.Mutation{SetJson: data}, nil
+	case "rdf":
+		if isDelete {
+			if err := ndgo.DeleteRDF(data).Validate(); err != nil {
+				return nil, err
+			}
+			return &api.Mutation{DelNquads: data}, nil
+		}
+		if err := ndgo.SetRDF(data).Validate(); err != nil {
+			return nil, err
+		}
+		return &api.Mutation{SetNquads: data}, nil
+	}
+	return nil, fmt.Errorf("unknown format %q, expected rdf, json or auto", format)
+}
+
+// mutationResult is printed by mutate and upsert
+type mutationResult struct {
+	Data json.RawMessage   `json:"data,omitempty"`
+	Uids map[string]string `json:"uids"`
+}
+
+func writeMutationResult(w io.Writer, resp *api.Response, compact bool) error {
+	res := mutationResult{Uids: resp.GetUids()}
+	if res.Uids == nil {
+		res.Uids = map[string]string{}
+	}
+	if data := resp.GetJson(); len(data) > 0 {
+		res.Data = data
+	}
+	b, err := json.Marshal(res)
+	if err != nil {
+		return err
+	}
+	return writeJSON(w, b, compact)
+}
+
+// --------------------------------------- upsert ---------------------------------------
+
+func runUpsert(args []string) error {
+	fs := flag.NewFlagSet("upsert", flag.ExitOnError)
+	var conn connFlags
+	conn.register(fs)
+	var out outFlags
+	out.register(fs)
+	var muf mutationFlags
+	muf.register(fs)
+	queryFile := fs.String("query", "", "file with upsert query, required")
+	cond := fs.String("cond", "", "mutation condition, i.e. '@if(eq(len(v), 0))'")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: ndgo upsert -query <file> [flags] [file]")
+		fmt.Fprintln(fs.Output(), "Reads mutation from stdin, if no file is given. The mutation is committed immediately.")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if *queryFile == "" {
+		fs.Usage()
+		return fmt.Errorf("-query is required")
+	}
+	file, err := inputArg(fs)
+	if err != nil {
+		return err
+	}
+	q, err := readInput(*queryFile)
+	if err != nil {
+		return err
+	}
+	data, err := readInput(file)
+	if err != nil {
+		return err
+	}
+	mu, err := mutation(data, muf.format, muf.delete)
+	if err != nil {
+		return err
+	}
+	mu.Cond = *cond
+
+	ctx, dg, cleanup, err := conn.connect()
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	txn := ndgo.NewTxn(ctx, dg.NewTxn())
+	defer txn.Discard()
+
+	resp, err := txn.Do(&api.Request{
+		Query:     string(q),
+		Mutations: []*api.Mutation{mu},
+		CommitNow: true,
+	})
+	if err != nil {
+		return err
+	}
+	err = writeMutationResult(os.Stdout, resp, out.compact)
+	out.printTimings(txn)
+	return err
+}
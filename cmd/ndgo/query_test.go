@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/dgraph-io/dgo/v210/protos/api"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVarsFlag(t *testing.T) {
+	vars := varsFlag{}
+	require.NoError(t, vars.Set("name=Keanu"))
+	require.NoError(t, vars.Set("$first=a=b"))
+	require.Error(t, vars.Set("=x"))
+	require.Error(t, vars.Set("x"))
+	require.Equal(t, varsFlag{"$name": "Keanu", "$first": "a=b"}, vars)
+	require.Equal(t, "$first=a=b,$name=Keanu", vars.String())
+}
+
+func TestMutation(t *testing.T) {
+	var testData = []struct {
+		in       string
+		format   string
+		isDelete bool
+		out      *api.Mutation
+		err      string
+	}{
+		{in: " _:a <name> \"x\" .\n", format: "auto", out: &api.Mutation{SetNquads: []byte(`_:a <name> "x" .`)}},
+		{in: `<0x1> * * .`, format: "auto", isDelete: true, out: &api.Mutation{DelNquads: []byte(`<0x1> * * .`)}},
+		{in: `[{"name": "x"}]`, format: "auto", out: &api.Mutation{SetJson: []byte(`[{"name": "x"}]`)}},
+		{in: `{"uid": "0x1"}`, format: "json", isDelete: true, out: &api.Mutation{DeleteJson: []byte(`{"uid": "0x1"}`)}},
+		{in: `{"name": }`, format: "auto", err: "invalid json mutation: invalid character '}' looking for beginning of value"},
+		{in: `_:a <name> "x"`, format: "rdf", err: `ndgo: rdf 1:15: expected '.'`},
+		{in: "  ", format: "auto", err: "empty mutation"},
+		{in: `_:a <name> "x" .`, format: "csv", err: `unknown format "csv", expected rdf, json or auto`},
+	}
+	for i, tt := range testData {
+		mu, err := mutation([]byte(tt.in), tt.format, tt.isDelete)
+		if tt.err != "" {
+			require.EqualError(t, err, tt.err, "Test i=%d", i)
+			continue
+		}
+		require.NoError(t, err, "Test i=%d", i)
+		require.Equal(t, tt.out, mu, "Test i=%d", i)
+	}
+}
+
+func TestWriteMutationResult(t *testing.T) {
+	var out bytes.Buffer
+	require.NoError(t, writeMutationResult(&out, &api.Response{Uids: map[string]string{"a": "0x1"}}, true))
+	require.Equal(t, `{"uids":{"a":"0x1"}}`+"\n", out.String())
+
+	out.Reset()
+	require.NoError(t, writeMutationResult(&out, &api.Response{Json: []byte(`{"q": []}`)}, false))
+	require.Equal(t, "{\n  \"data\": {\n    \"q\": []\n  },\n  \"uids\": {}\n}\n", out.String())
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/dgraph-io/dgo/v210/protos/api"
+	"github.com/ppp225/ndgo/v5"
+)
+
+func init() {
+	commands["schema"] = command{
+		usage: "print schema of all or given predicates",
+		run:   runSchema,
+	}
+	commands["alter"] = command{
+		usage: "alter schema from file or stdin",
+		run:   runAlter,
+	}
+	commands["drop"] = command{
+		usage: "drop all, data, a predicate or a type",
+		run:   runDrop,
+	}
+}
+
+// --------------------------------------- schema ---------------------------------------
+
+func runSchema(args []string) error {
+	fs := flag.NewFlagSet("schema", flag.ExitOnError)
+	var conn connFlags
+	conn.register(fs)
+	var out outFlags
+	out.register(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: ndgo schema [flags] [predicate]...")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	ctx, dg, cleanup, err := conn.connect()
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	txn := ndgo.NewTxn(ctx, dg.NewReadOnlyTxn())
+	defer txn.Discard()
+
+	resp, err := txn.Query(schemaQuery(fs.Args()))
+	if err != nil {
+		return err
+	}
+	err = writeJSON(os.Stdout, resp.GetJson(), out.compact)
+	out.printTimings(txn)
+	return err
+}
+
+// schemaQuery returns `schema {}` query, limited to preds, if any
+func schemaQuery(preds []string) string {
+	if len(preds) == 0 {
+		return `schema {}`
+	}
+	return fmt.Sprintf(`schema(pred: [%s]) {}`, strings.Join(preds, ", "))
+}
+
+// --------------------------------------- alter ---------------------------------------
+
+func runAlter(args []string) error {
+	fs := flag.NewFlagSet("alter", flag.ExitOnError)
+	var conn connFlags
+	conn.register(fs)
+	var out outFlags
+	out.register(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: ndgo alter [flags] [file]")
+		fmt.Fprintln(fs.Output(), "Reads stdin, if no file is given.")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	file, err := inputArg(fs)
+	if err != nil {
+		return err
+	}
+	schema, err := readInput(file)
+	if err != nil {
+		return err
+	}
+	if _, err := ndgo.ParseSchema(string(schema)); err != nil {
+		return err
+	}
+
+	ctx, dg, cleanup, err := conn.connect()
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	t := time.Now()
+	if err := dg.Alter(ctx, &api.Operation{Schema: string(schema)}); err != nil {
+		return err
+	}
+	out.printElapsed(t)
+	return nil
+}
+
+// printElapsed prints network time of operations which don't go through a Txn
+func (v *outFlags) printElapsed(start time.Time) {
+	if !v.quiet {
+		fmt.Fprintf(os.Stderr, "nw %.2fms\n", float64(time.Since(start).Nanoseconds())/1e6)
+	}
+}
+
+// --------------------------------------- drop ---------------------------------------
+
+func runDrop(args []string) error {
+	fs := flag.NewFlagSet("drop", flag.ExitOnError)
+	var conn connFlags
+	conn.register(fs)
+	var out outFlags
+	out.register(fs)
+	all := fs.Bool("all", false, "drop all data and schema")
+	data := fs.Bool("data", false, "drop all data, keep schema")
+	pred := fs.String("pred", "", "drop predicate with its data")
+	typ := fs.String("type", "", "drop type definition")
+	yes := fs.Bool("y", false, "don't ask for confirmation")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: ndgo drop [flags] -all | -data | -pred <name> | -type <name>")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	op, desc, err := dropOperation(*all, *data, *pred, *typ)
+	if err != nil {
+		fs.Usage()
+		return err
+	}
+	if !*yes && !confirm(os.Stdin, os.Stderr, desc) {
+		return fmt.Errorf("aborted")
+	}
+
+	ctx, dg, cleanup, err := conn.connect()
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	t := time.Now()
+	if err := dg.Alter(ctx, op); err != nil {
+		return err
+	}
+	out.printElapsed(t)
+	return nil
+}
+
+// dropOperation builds drop operation and its description. Exactly one kind of drop must be set.
+func dropOperation(all, data bool, pred, typ string) (op *api.Operation, desc string, err error) {
+	n := 0
+	if all {
+		n++
+		op, desc = &api.Operation{DropOp: api.Operation_ALL}, "all data and schema"
+	}
+	if data {
+		n++
+		op, desc = &api.Operation{DropOp: api.Operation_DATA}, "all data"
+	}
+	if pred != "" {
+		n++
+		op, desc = &api.Operation{DropOp: api.Operation_ATTR, DropValue: pred}, "predicate "+pred
+	}
+	if typ != "" {
+		n++
+		op, desc = &api.Operation{DropOp: api.Operation_TYPE, DropValue: typ}, "type "+typ
+	}
+	if n != 1 {
+		return nil, "", fmt.Errorf("expected exactly one of -all, -data, -pred or -type")
+	}
+	return op, desc, nil
+}
+
+// confirm asks whether to drop desc, and reports whether user answered yes
+func confirm(in io.Reader, out io.Writer, desc string) bool {
+	fmt.Fprintf(out, "Drop %s? [y/N] ", desc)
+	answer, _ := bufio.NewReader(in).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
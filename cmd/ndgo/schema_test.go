@@ -0,0 +1,39 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/dgraph-io/dgo/v210/protos/api"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchemaQuery(t *testing.T) {
+	require.Equal(t, `schema {}`, schemaQuery(nil))
+	require.Equal(t, `schema(pred: [name, age]) {}`, schemaQuery([]string{"name", "age"}))
+}
+
+func TestDropOperation(t *testing.T) {
+	op, desc, err := dropOperation(false, false, "name", "")
+	require.NoError(t, err)
+	require.Equal(t, &api.Operation{DropOp: api.Operation_ATTR, DropValue: "name"}, op)
+	require.Equal(t, "predicate name", desc)
+
+	op, _, err = dropOperation(true, false, "", "")
+	require.NoError(t, err)
+	require.Equal(t, &api.Operation{DropOp: api.Operation_ALL}, op)
+
+	_, _, err = dropOperation(false, false, "", "")
+	require.Error(t, err)
+	_, _, err = dropOperation(false, true, "", "Person")
+	require.Error(t, err)
+}
+
+func TestConfirm(t *testing.T) {
+	var out bytes.Buffer
+	require.True(t, confirm(strings.NewReader("Yes\n"), &out, "all data"))
+	require.Equal(t, "Drop all data? [y/N] ", out.String())
+	require.False(t, confirm(strings.NewReader("\n"), &out, "all data"))
+	require.False(t, confirm(strings.NewReader(""), &out, "all data"))
+}
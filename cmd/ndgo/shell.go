@@ -0,0 +1,387 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/dgraph-io/dgo/v210"
+	"github.com/ppp225/ndgo/v5"
+)
+
+func init() {
+	commands["shell"] = command{
+		usage: "interactive DQL shell with transaction control",
+		run:   runShell,
+	}
+}
+
+const shellHelp = `Statements end when brackets are balanced, so they can span multiple lines.
+
+  { q(func: ...) { ... } }      run query, also 'query name($a: string) { ... }' and 'schema {}'
+  set { <N-Quads> }             set N-Quads or json, i.e. 'set [{"name": "x"}]'
+  delete { <N-Quads> }          delete N-Quads or json
+  begin                         start transaction, statements run in it until commit or discard
+  commit                        commit transaction
+  discard                       discard transaction
+  uids                          print uids assigned in transaction, or by last mutation
+  history                       print history, '!n' runs n-th statement again
+  help                          print this help
+  exit                          exit, discarding open transaction
+
+Outside of a transaction, queries run in read-only transactions and mutations are committed immediately.`
+
+var errShellExit = errors.New("exit")
+
+func runShell(args []string) error {
+	fs := flag.NewFlagSet("shell", flag.ExitOnError)
+	var conn connFlags
+	conn.register(fs)
+	compact := fs.Bool("compact", false, "print json on a single line")
+	historyFile := fs.String("history", defaultHistoryFile(), "history file, empty disables persistent history")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: ndgo shell [flags]")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	ctx, dg, cleanup, err := conn.connect()
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	sh := &shell{
+		ctx:         ctx,
+		dg:          dg,
+		out:         os.Stdout,
+		errOut:      os.Stderr,
+		compact:     *compact,
+		historyFile: *historyFile,
+	}
+	sh.loadHistory()
+	fmt.Fprintf(sh.out, "Connected to %s. Type 'help' for help.\n", conn.addr)
+	return sh.run(os.Stdin)
+}
+
+func defaultHistoryFile() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".ndgo_history")
+}
+
+// --------------------------------------- shell ---------------------------------------
+
+// shell runs statements read from input. Statements run in txn, if a transaction is open,
+// otherwise each runs in its own transaction.
+type shell struct {
+	ctx     context.Context
+	dg      *dgo.Dgraph
+	out     io.Writer
+	errOut  io.Writer
+	compact bool
+
+	txn  *ndgo.Txn
+	uids map[string]string // assigned in txn, or by last mutation outside of it
+
+	history     []string
+	historyFile string
+}
+
+func (v *shell) run(in io.Reader) error {
+	sc := bufio.NewScanner(in)
+	sc.Buffer(make([]byte, 64*1024), 16<<20)
+	var r statementReader
+	v.prompt(false)
+	for sc.Scan() {
+		stmt, ok := r.add(sc.Text())
+		if !ok {
+			v.prompt(true)
+			continue
+		}
+		if stmt != "" {
+			if err := v.exec(stmt); err == errShellExit {
+				break
+			} else if err != nil {
+				fmt.Fprintf(v.errOut, "error: %v\n", err)
+			}
+		}
+		v.prompt(false)
+	}
+	if v.txn != nil {
+		v.txn.Discard()
+		fmt.Fprintln(v.errOut, "open transaction discarded")
+	}
+	return sc.Err()
+}
+
+func (v *shell) prompt(continuation bool) {
+	switch {
+	case continuation:
+		fmt.Fprint(v.out, "   ...> ")
+	case v.txn != nil:
+		fmt.Fprint(v.out, "ndgo(txn)> ")
+	default:
+		fmt.Fprint(v.out, "ndgo> ")
+	}
+}
+
+// exec runs a single complete statement
+func (v *shell) exec(stmt string) error {
+	if strings.HasPrefix(stmt, "!") {
+		n, err := strconv.Atoi(stmt[1:])
+		if err != nil || n < 1 || n > len(v.history) {
+			return fmt.Errorf("no statement %s in history", stmt)
+		}
+		stmt = v.history[n-1]
+		fmt.Fprintln(v.out, stmt)
+	}
+	keyword, body := splitKeyword(stmt)
+	if keyword != "history" {
+		v.addHistory(stmt)
+	}
+
+	switch keyword {
+	case "help":
+		fmt.Fprintln(v.out, shellHelp)
+	case "exit", "quit":
+		return errShellExit
+	case "history":
+		for i, s := range v.history {
+			fmt.Fprintf(v.out, "%4d  %s\n", i+1, s)
+		}
+	case "begin":
+		if v.txn != nil {
+			return fmt.Errorf("transaction already open, commit or discard it first")
+		}
+		v.txn = ndgo.NewTxn(v.ctx, v.dg.NewTxn())
+		v.uids = map[string]string{}
+	case "commit", "discard":
+		if v.txn == nil {
+			return fmt.Errorf("no open transaction")
+		}
+		txn := v.txn
+		v.txn = nil
+		var err error
+		done := "discarded"
+		if keyword == "commit" {
+			err = txn.Commit()
+			done = "committed"
+		}
+		txn.Discard()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(v.errOut, "%s, transaction total db %.2fms, nw %.2fms\n", done, txn.GetDatabaseTime(), txn.GetNetworkTime())
+	case "uids":
+		b, err := json.Marshal(v.uids)
+		if err != nil {
+			return err
+		}
+		return writeJSON(v.out, b, v.compact)
+	case "set", "delete":
+		return v.mutate(body, keyword == "delete")
+	case "{", "query", "schema":
+		return v.query(stmt)
+	default:
+		return fmt.Errorf("unknown statement %q, type 'help' for help", keyword)
+	}
+	return nil
+}
+
+func (v *shell) query(q string) error {
+	txn := v.txn
+	if txn == nil {
+		txn = ndgo.NewTxn(v.ctx, v.dg.NewReadOnlyTxn())
+		defer txn.Discard()
+	}
+	db, nw := txn.GetDatabaseTime(), txn.GetNetworkTime()
+	resp, err := txn.Query(q)
+	if err != nil {
+		return err
+	}
+	if err := writeJSON(v.out, resp.GetJson(), v.compact); err != nil {
+		return err
+	}
+	v.printLatency(txn, db, nw)
+	return nil
+}
+
+func (v *shell) mutate(body string, isDelete bool) error {
+	mu, err := mutation([]byte(shellMutationBody(body)), "auto", isDelete)
+	if err != nil {
+		return err
+	}
+	txn := v.txn
+	if txn == nil {
+		txn = ndgo.NewTxn(v.ctx, v.dg.NewTxn())
+		defer txn.Discard()
+		mu.CommitNow = true
+		v.uids = map[string]string{}
+	}
+	db, nw := txn.GetDatabaseTime(), txn.GetNetworkTime()
+	resp, err := txn.Mutate(mu)
+	if err != nil {
+		return err
+	}
+	for k, uid := range resp.GetUids() {
+		v.uids[k] = uid
+	}
+	if err := writeMutationResult(v.out, resp, v.compact); err != nil {
+		return err
+	}
+	v.printLatency(txn, db, nw)
+	return nil
+}
+
+// printLatency prints time of the last statement, given txn times before it
+func (v *shell) printLatency(txn *ndgo.Txn, db, nw float64) {
+	fmt.Fprintf(v.errOut, "db %.2fms, nw %.2fms\n", txn.GetDatabaseTime()-db, txn.GetNetworkTime()-nw)
+}
+
+// splitKeyword splits statement into its lowercased first word and the rest.
+// A leading `{` is a keyword on its own, as in `{ q(func: ...) }`.
+func splitKeyword(stmt string) (keyword, body string) {
+	stmt = strings.TrimSpace(stmt)
+	if strings.HasPrefix(stmt, "{") {
+		return "{", stmt
+	}
+	idx := strings.IndexFunc(stmt, func(r rune) bool {
+		return r == ' ' || r == '\t' || r == '\n' || r == '{' || r == '[' || r == '('
+	})
+	if idx < 0 {
+		return strings.ToLower(stmt), ""
+	}
+	return strings.ToLower(stmt[:idx]), strings.TrimSpace(stmt[idx:])
+}
+
+// shellMutationBody unwraps N-Quads from `{ ... }`, as in Ratel. Json objects are returned as is.
+func shellMutationBody(body string) string {
+	if strings.HasPrefix(body, "{") && strings.HasSuffix(body, "}") && !json.Valid([]byte(body)) {
+		return strings.TrimSpace(body[1 : len(body)-1])
+	}
+	return body
+}
+
+// --------------------------------------- history ---------------------------------------
+
+const maxHistory = 1000
+
+// loadHistory reads history file, which contains one quoted statement per line
+func (v *shell) loadHistory() {
+	if v.historyFile == "" {
+		return
+	}
+	f, err := os.Open(v.historyFile)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 64*1024), 16<<20)
+	for sc.Scan() {
+		if s, err := strconv.Unquote(sc.Text()); err == nil {
+			v.history = append(v.history, s)
+		}
+	}
+	if len(v.history) > maxHistory {
+		v.history = v.history[len(v.history)-maxHistory:]
+	}
+}
+
+func (v *shell) addHistory(stmt string) {
+	if n := len(v.history); n > 0 && v.history[n-1] == stmt {
+		return
+	}
+	v.history = append(v.history, stmt)
+	if v.historyFile == "" {
+		return
+	}
+	f, err := os.OpenFile(v.historyFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	fmt.Fprintln(f, strconv.Quote(stmt))
+}
+
+// --------------------------------------- statement reader ---------------------------------------
+
+// statementReader joins input lines into statements. A statement is complete, when its brackets are balanced,
+// and it has a body: a `{` for queries, or anything after set and delete. Other statements are single words.
+type statementReader struct {
+	lines    []string
+	depth    int
+	inString bool
+	escaped  bool
+	braces   bool // `{` seen outside of string
+}
+
+// add adds line, and returns complete statement and true, or false if more lines are needed
+func (v *statementReader) add(line string) (string, bool) {
+	if len(v.lines) == 0 && strings.TrimSpace(line) == "" {
+		return "", true
+	}
+	v.lines = append(v.lines, line)
+	v.scan(line)
+	stmt := strings.TrimSpace(strings.Join(v.lines, "\n"))
+	if v.depth > 0 || v.inString {
+		return "", false
+	}
+	keyword, body := splitKeyword(stmt)
+	switch keyword {
+	case "set", "delete":
+		if body == "" {
+			return "", false
+		}
+	case "{", "query", "schema":
+		if !v.braces {
+			return "", false
+		}
+	}
+	*v = statementReader{}
+	return stmt, true
+}
+
+// scan updates bracket depth with line. `#` comments are skipped, unless inside a string or a word, like <a#b>.
+func (v *statementReader) scan(line string) {
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		if v.inString {
+			switch {
+			case v.escaped:
+				v.escaped = false
+			case c == '\\':
+				v.escaped = true
+			case c == '"':
+				v.inString = false
+			}
+			continue
+		}
+		switch c {
+		case '"':
+			v.inString = true
+		case '#':
+			if i == 0 || line[i-1] == ' ' || line[i-1] == '\t' {
+				return
+			}
+		case '{':
+			v.braces = true
+			v.depth++
+		case '[', '(':
+			v.depth++
+		case '}', ']', ')':
+			v.depth--
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatementReader(t *testing.T) {
+	var testData = []struct {
+		in  []string
+		out string
+	}{
+		{in: []string{"begin"}, out: "begin"},
+		{in: []string{"{", "  q(func: has(name)) {", "    uid # }", "  }", "}"}, out: "{\n  q(func: has(name)) {\n    uid # }\n  }\n}"},
+		{in: []string{"query x($a: string)", "{ q(func: eq(name, $a)) { uid } }"}, out: "query x($a: string)\n{ q(func: eq(name, $a)) { uid } }"},
+		{in: []string{"set", `{ _:a <name> "}" . }`}, out: "set\n{ _:a <name> \"}\" . }"},
+		{in: []string{`set _:a <http://x#y> "a" .`}, out: `set _:a <http://x#y> "a" .`},
+		{in: []string{`set [{"name":`, `"x"}]`}, out: "set [{\"name\":\n\"x\"}]"},
+	}
+	for i, tt := range testData {
+		var r statementReader
+		for j, line := range tt.in {
+			stmt, ok := r.add(line)
+			if j < len(tt.in)-1 {
+				require.False(t, ok, "Test i=%d line=%d", i, j)
+				continue
+			}
+			require.True(t, ok, "Test i=%d", i)
+			require.Equal(t, tt.out, stmt, "Test i=%d", i)
+		}
+	}
+}
+
+func TestShellMutationBody(t *testing.T) {
+	require.Equal(t, `_:a <name> "x" .`, shellMutationBody(`{ _:a <name> "x" . }`))
+	require.Equal(t, `{"name": "x"}`, shellMutationBody(`{"name": "x"}`))
+	require.Equal(t, `[{"name": "x"}]`, shellMutationBody(`[{"name": "x"}]`))
+	keyword, body := splitKeyword("SET{ _:a <name> \"x\" . }")
+	require.Equal(t, "set", keyword)
+	require.Equal(t, `{ _:a <name> "x" . }`, body)
+}
+
+func TestShellRun(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ndgo-shell")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "history")
+
+	var out, errOut bytes.Buffer
+	sh := &shell{out: &out, errOut: &errOut, historyFile: file}
+	in := strings.Join([]string{"help", "", "commit", "select *", "history", "!1", "exit", "help"}, "\n")
+	require.NoError(t, sh.run(strings.NewReader(in)))
+	require.Equal(t, 2, strings.Count(out.String(), "begin  "), "help should be printed twice, by help and !1")
+	require.Contains(t, out.String(), "   1  help\n   2  commit\n   3  select *\n")
+	require.Equal(t, "error: no open transaction\nerror: unknown statement \"select\", type 'help' for help\n", errOut.String())
+
+	sh = &shell{historyFile: file}
+	sh.loadHistory()
+	require.Equal(t, []string{"help", "commit", "select *", "help", "exit"}, sh.history)
+}
@@ -0,0 +1,278 @@
+package ndgo
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// --------------------------------------- json to n-quads ---------------------------------------
+
+// JSONToNQuads converts JSON set mutation (object or array of objects, as produced by Seti) to N-Quads.
+// Nested objects become edges, objects without uid get generated blank nodes `_:ndgo.N`,
+// `pred|facet` keys become facets and `pred@lang` keys language tagged literals. Null values are skipped.
+func JSONToNQuads(data []byte) ([]NQuad, error) {
+	return jsonToNQuads(data, false)
+}
+
+// ToRDF converts SetJSON to equivalent SetRDF
+func (v SetJSON) ToRDF() (SetRDF, error) {
+	nquads, err := jsonToNQuads(wrapJSONArray(string(v)), false)
+	return SetRDF(formatNQuads(nquads)), err
+}
+
+// ToRDF converts DeleteJSON to equivalent DeleteRDF. Objects with only uid delete the whole node, null values delete predicates.
+// All objects, including nested ones, must have uid.
+func (v DeleteJSON) ToRDF() (DeleteRDF, error) {
+	nquads, err := jsonToNQuads(wrapJSONArray(string(v)), true)
+	return DeleteRDF(formatNQuads(nquads)), err
+}
+
+func wrapJSONArray(s string) []byte {
+	return []byte("[" + s + "]")
+}
+
+func formatNQuads(nquads []NQuad) string {
+	var b strings.Builder
+	for _, nq := range nquads {
+		b.WriteString(nq.String())
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+func jsonToNQuads(data []byte, isDelete bool) ([]NQuad, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var val interface{}
+	if err := dec.Decode(&val); err != nil {
+		return nil, err
+	}
+	c := jsonConverter{isDelete: isDelete}
+	switch v := val.(type) {
+	case map[string]interface{}:
+		if _, err := c.object(v, false); err != nil {
+			return nil, err
+		}
+	case []interface{}:
+		for _, item := range v {
+			obj, ok := item.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("ndgo: json mutation array must contain objects, got %T", item)
+			}
+			if _, err := c.object(obj, false); err != nil {
+				return nil, err
+			}
+		}
+	default:
+		return nil, fmt.Errorf("ndgo: json mutation must be an object or array, got %T", val)
+	}
+	return c.nquads, nil
+}
+
+type jsonConverter struct {
+	isDelete bool
+	blanks   int
+	nquads   []NQuad
+}
+
+// object converts a single node and returns its subject. Nested objects only reference nodes, so they never delete them.
+func (v *jsonConverter) object(obj map[string]interface{}, nested bool) (string, error) {
+	subject, _ := obj["uid"].(string)
+	if subject == "" && v.isDelete {
+		return "", fmt.Errorf("ndgo: json delete object must have uid, got %v", obj)
+	}
+	if subject == "" {
+		subject = fmt.Sprintf("_:ndgo.%d", v.blanks)
+		v.blanks++
+	}
+	keys := make([]string, 0, len(obj))
+	for key := range obj {
+		if key != "uid" && !strings.Contains(key, "|") {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) == 0 && v.isDelete && !nested {
+		v.nquads = append(v.nquads, NQuad{Subject: subject, Predicate: "*", ObjectID: "*"})
+		return subject, nil
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		pred, lang := key, ""
+		if idx := strings.IndexByte(key, '@'); idx >= 0 {
+			pred, lang = key[:idx], key[idx+1:]
+		}
+		facets := facetsOf(obj, key)
+		values, isList := obj[key].([]interface{})
+		if !isList {
+			values = []interface{}{obj[key]}
+		}
+		for i, val := range values {
+			nq := NQuad{Subject: subject, Predicate: pred}
+			switch value := val.(type) {
+			case nil:
+				if !v.isDelete {
+					continue // null in set is ignored by dgraph too
+				}
+				nq.ObjectID = "*"
+			case map[string]interface{}:
+				if isGeoJSON(value) {
+					b, err := json.Marshal(value)
+					if err != nil {
+						return "", err
+					}
+					nq.ObjectValue, nq.Datatype = string(b), GeoJSON
+					nq.Facets = facets.at(i, isList)
+					break
+				}
+				child, err := v.object(value, true)
+				if err != nil {
+					return "", err
+				}
+				nq.ObjectID = child
+				nq.Facets = facetsOf(value, key).at(0, false)
+			case string:
+				nq.ObjectValue, nq.Lang = value, lang
+				nq.Facets = facets.at(i, isList)
+			case json.Number:
+				nq.ObjectValue, nq.Datatype = value.String(), XSInt
+				if _, err := value.Int64(); err != nil {
+					nq.Datatype = XSFloat
+				}
+				nq.Facets = facets.at(i, isList)
+			case bool:
+				nq.ObjectValue, nq.Datatype = strconv.FormatBool(value), XSBoolean
+				nq.Facets = facets.at(i, isList)
+			default:
+				return "", fmt.Errorf("ndgo: unsupported value of %s: %T", key, val)
+			}
+			v.nquads = append(v.nquads, nq)
+		}
+	}
+	return subject, nil
+}
+
+// --------------------------------------- n-quads to json ---------------------------------------
+
+// NQuadsToJSON converts N-Quads to JSON mutation: an array with one object per subject, in order of appearance.
+// Edges become `{"uid": ...}` objects, wildcard objects become null, `<s> * * .` becomes `{"uid": s}`.
+// Predicates occurring multiple times for the same subject become lists.
+func NQuadsToJSON(nquads []NQuad) ([]byte, error) {
+	var order []string
+	objects := map[string]map[string]interface{}{}
+	counts := map[string]map[string]int{}
+	for _, nq := range nquads {
+		obj, ok := objects[nq.Subject]
+		if !ok {
+			obj = map[string]interface{}{"uid": nq.Subject}
+			objects[nq.Subject] = obj
+			counts[nq.Subject] = map[string]int{}
+			order = append(order, nq.Subject)
+		}
+		if nq.Predicate == "*" {
+			continue
+		}
+		key := nq.Predicate
+		if nq.Lang != "" {
+			key += "@" + nq.Lang
+		}
+
+		var val interface{}
+		switch {
+		case nq.ObjectID == "*":
+			obj[key] = nil
+			continue
+		case !nq.IsLiteral():
+			child := map[string]interface{}{"uid": nq.ObjectID}
+			for _, f := range nq.Facets {
+				child[key+"|"+f.Key] = facetJSONValue(f.Value)
+			}
+			val = child
+		default:
+			typed, err := nq.Value()
+			if err != nil {
+				return nil, fmt.Errorf("ndgo: %s: %w", nq.String(), err)
+			}
+			if t, ok := typed.(time.Time); ok {
+				typed = t.Format(time.RFC3339Nano)
+			}
+			val = typed
+		}
+
+		n := counts[nq.Subject][key]
+		counts[nq.Subject][key] = n + 1
+		switch n {
+		case 0:
+			obj[key] = val
+		case 1:
+			obj[key] = []interface{}{obj[key], val}
+		default:
+			obj[key] = append(obj[key].([]interface{}), val)
+		}
+		if nq.IsLiteral() {
+			setScalarFacets(obj, key, n, nq.Facets)
+		}
+	}
+
+	res := make([]map[string]interface{}, len(order))
+	for i, subject := range order {
+		res[i] = objects[subject]
+	}
+	return json.Marshal(res)
+}
+
+// ToJSON converts SetRDF to equivalent SetJSON
+func (v SetRDF) ToJSON() (SetJSON, error) {
+	nquads, err := ParseNQuads(string(v))
+	if err != nil {
+		return "", err
+	}
+	b, err := NQuadsToJSON(nquads)
+	return SetJSON(unwrapJSONArray(b)), err
+}
+
+// ToJSON converts DeleteRDF to equivalent DeleteJSON
+func (v DeleteRDF) ToJSON() (DeleteJSON, error) {
+	nquads, err := ParseNQuads(string(v))
+	if err != nil {
+		return "", err
+	}
+	b, err := NQuadsToJSON(nquads)
+	return DeleteJSON(unwrapJSONArray(b)), err
+}
+
+// setScalarFacets sets facets of n-th value of key. Once a key has multiple values, facets are indexed maps, i.e. `{"0": "a"}`.
+func setScalarFacets(obj map[string]interface{}, key string, n int, facets []Facet) {
+	if n == 1 { // value became a list, convert existing facets to indexed maps
+		for k, val := range obj {
+			if strings.HasPrefix(k, key+"|") {
+				obj[k] = map[string]interface{}{"0": val}
+			}
+		}
+	}
+	for _, f := range facets {
+		fk := key + "|" + f.Key
+		if n == 0 {
+			obj[fk] = facetJSONValue(f.Value)
+			continue
+		}
+		indexed, ok := obj[fk].(map[string]interface{})
+		if !ok {
+			indexed = map[string]interface{}{}
+			obj[fk] = indexed
+		}
+		indexed[strconv.Itoa(n)] = facetJSONValue(f.Value)
+	}
+}
+
+func facetJSONValue(val interface{}) interface{} {
+	if t, ok := val.(time.Time); ok {
+		return t.Format(time.RFC3339Nano)
+	}
+	return val
+}
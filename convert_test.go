@@ -0,0 +1,108 @@
+package ndgo_test
+
+import (
+	"testing"
+
+	"github.com/ppp225/ndgo/v5"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJSONToNQuads(t *testing.T) {
+	nquads, err := ndgo.JSONToNQuads([]byte(`[{
+		"uid": "_:a",
+		"dgraph.type": ["Person", "Actor"],
+		"name": "Keanu",
+		"name@de": "Keanu DE",
+		"name|origin": "birth",
+		"age": 56,
+		"score": 9.5,
+		"active": true,
+		"deleted": null,
+		"nick": ["neo", "john"],
+		"nick|since": {"1": 2014},
+		"loc": {"type": "Point", "coordinates": [1.5, 2.5]},
+		"friend": [{"uid": "0x2", "friend|weight": 0.5}, {"name": "new friend"}]
+	}]`))
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		`_:a <active> "true"^^<xs:boolean> .`,
+		`_:a <age> "56"^^<xs:int> .`,
+		`_:a <dgraph.type> "Person" .`,
+		`_:a <dgraph.type> "Actor" .`,
+		`_:a <friend> <0x2> (weight=0.5) .`,
+		`_:ndgo.0 <name> "new friend" .`,
+		`_:a <friend> _:ndgo.0 .`,
+		`_:a <loc> "{\"coordinates\":[1.5,2.5],\"type\":\"Point\"}"^^<geo:geojson> .`,
+		`_:a <name> "Keanu" (origin="birth") .`,
+		`_:a <name> "Keanu DE"@de .`,
+		`_:a <nick> "neo" .`,
+		`_:a <nick> "john" (since=2014) .`,
+		`_:a <score> "9.5"^^<xs:float> .`,
+	}, nquadStrings(nquads))
+
+	_, err = ndgo.JSONToNQuads([]byte(`"string"`))
+	require.Error(t, err)
+	_, err = ndgo.JSONToNQuads([]byte(`[1]`))
+	require.Error(t, err)
+}
+
+func TestDeleteJSONToRDF(t *testing.T) {
+	rdf, err := ndgo.DeleteJSON(`{"uid": "0x1"}, {"uid": "0x2", "name": null, "friend": {"uid": "0x3"}}`).ToRDF()
+	require.NoError(t, err)
+	require.Equal(t, ndgo.DeleteRDF("<0x1> * * .\n<0x2> <friend> <0x3> .\n<0x2> <name> * .\n"), rdf)
+	require.NoError(t, rdf.Validate())
+
+	json, err := rdf.ToJSON()
+	require.NoError(t, err)
+	require.Equal(t, ndgo.DeleteJSON(`{"uid":"0x1"},{"friend":{"uid":"0x3"},"name":null,"uid":"0x2"}`), json)
+
+	_, err = ndgo.DeleteJSON(`{"uid": "0x1", "friend": {"name": "new friend"}}`).ToRDF()
+	require.EqualError(t, err, "ndgo: json delete object must have uid, got map[name:new friend]")
+}
+
+func TestNQuadsToJSON(t *testing.T) {
+	set := ndgo.SetRDF(`
+		_:a <name> "Keanu" (origin="birth") .
+		_:a <name> "Keanu DE"@de .
+		_:a <age> "56"^^<xs:int> .
+		_:a <nick> "neo" (since=2014) .
+		_:a <nick> "john" .
+		_:a <nick> "jw" (since=2019) .
+		_:a <friend> <0x2> (weight=0.5) .
+		<0x2> <born> "1964-09-02T00:00:00Z"^^<xs:dateTime> .
+		<0x2> <loc> "{\"type\":\"Point\",\"coordinates\":[1,2]}"^^<geo:geojson> .
+	`)
+	json, err := set.ToJSON()
+	require.NoError(t, err)
+	require.JSONEq(t, `[{
+		"uid": "_:a",
+		"name": "Keanu",
+		"name|origin": "birth",
+		"name@de": "Keanu DE",
+		"age": 56,
+		"nick": ["neo", "john", "jw"],
+		"nick|since": {"0": 2014, "2": 2019},
+		"friend": {"uid": "0x2", "friend|weight": 0.5}
+	}, {
+		"uid": "0x2",
+		"born": "1964-09-02T00:00:00Z",
+		"loc": {"type": "Point", "coordinates": [1, 2]}
+	}]`, "["+string(json)+"]")
+
+	// round trip, datetime and geo are not compared, as json does not keep their type and key order
+	rdf, err := json.ToRDF()
+	require.NoError(t, err)
+	nquads, err := ndgo.ParseNQuads(string(set))
+	require.NoError(t, err)
+	roundTrip, err := ndgo.ParseNQuads(string(rdf))
+	require.NoError(t, err)
+	require.ElementsMatch(t, nquadStrings(nquads[:7]), nquadStrings(roundTrip[:7]))
+}
+
+func nquadStrings(nquads []ndgo.NQuad) []string {
+	res := make([]string, len(nquads))
+	for i, nq := range nquads {
+		res[i] = nq.String()
+	}
+	return res
+}
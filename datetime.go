@@ -0,0 +1,75 @@
+package ndgo
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// --------------------------------------- datetime ---------------------------------------
+
+// DateTime is a value of a datetime predicate. It marshals to RFC3339 with nanoseconds, or null when zero,
+// so unset DateTime fields aren't stored as year 1. Marshal omits zero DateTime fields instead, so they don't delete
+// the predicate in delete mutations. It decodes all formats dgraph accepts, i.e. `2006-01-02`.
+type DateTime struct {
+	time.Time
+}
+
+var dateTimeType = reflect.TypeOf(DateTime{})
+
+// dateTimeLayouts are formats of datetime values accepted by dgraph
+var dateTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02T15:04",
+	"2006-01-02",
+	"2006-01",
+	"2006",
+}
+
+// ParseDateTime parses datetime value in any format dgraph accepts
+func ParseDateTime(s string) (DateTime, error) {
+	for _, layout := range dateTimeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return DateTime{t}, nil
+		}
+	}
+	return DateTime{}, fmt.Errorf("ndgo: invalid datetime %q", s)
+}
+
+// String formats v as RFC3339 with nanoseconds, as used in N-Quads with xs:dateTime datatype
+func (v DateTime) String() string {
+	return v.Format(time.RFC3339Nano)
+}
+
+// MarshalJSON encodes RFC3339 string, or null when zero
+func (v DateTime) MarshalJSON() ([]byte, error) {
+	if v.IsZero() {
+		return []byte("null"), nil
+	}
+	return json.Marshal(v.String())
+}
+
+// UnmarshalJSON decodes datetime string in any format dgraph accepts
+func (v *DateTime) UnmarshalJSON(data []byte) error {
+	var s *string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("ndgo: datetime must be string: %w", err)
+	}
+	if s == nil || *s == "" {
+		*v = DateTime{}
+		return nil
+	}
+	t, err := ParseDateTime(*s)
+	if err != nil {
+		return err
+	}
+	*v = t
+	return nil
+}
+
+// SetDateTime Usage: ndgo.Query{}.SetDateTime(uid, predicate, time.Now())
+func (Query) SetDateTime(uid, predicate string, t time.Time) SetRDF {
+	return SetRDF(NQuad{Subject: uid, Predicate: predicate, ObjectValue: DateTime{t}.String(), Datatype: XSDateTime}.String() + "\n")
+}
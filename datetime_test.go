@@ -0,0 +1,85 @@
+package ndgo_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dgraph-io/dgo/v210/protos/api"
+	"github.com/ppp225/ndgo/v5"
+	"github.com/stretchr/testify/require"
+)
+
+type event struct {
+	At    ndgo.DateTime `json:"at"`
+	Until ndgo.DateTime `json:"until"`
+}
+
+func TestDateTime(t *testing.T) {
+	at := time.Date(2021, 3, 4, 5, 6, 7, 800, time.UTC)
+	b, err := ndgo.Marshal(event{At: ndgo.DateTime{Time: at}})
+	require.NoError(t, err)
+	require.JSONEq(t, `{"at": "2021-03-04T05:06:07.0000008Z"}`, string(b), "zero DateTime should be omitted")
+
+	var decoded event
+	require.NoError(t, ndgo.Unmarshal(b, &decoded))
+	require.True(t, at.Equal(decoded.At.Time))
+	require.True(t, decoded.Until.IsZero())
+
+	var testData = []struct {
+		in  string
+		out time.Time
+	}{
+		{in: "2021-03-04T05:06:07+02:00", out: time.Date(2021, 3, 4, 3, 6, 7, 0, time.UTC)},
+		{in: "2021-03-04T05:06:07", out: time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)},
+		{in: "2021-03-04", out: time.Date(2021, 3, 4, 0, 0, 0, 0, time.UTC)},
+		{in: "2021", out: time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range testData {
+		out, err := ndgo.ParseDateTime(tt.in)
+		require.NoError(t, err, tt.in)
+		require.True(t, tt.out.Equal(out.Time), tt.in)
+	}
+	_, err = ndgo.ParseDateTime("04.03.2021")
+	require.Error(t, err)
+	require.Error(t, ndgo.Unmarshal([]byte(`{"at": 5}`), &decoded))
+
+	set := ndgo.Query{}.SetDateTime("0x1", "at", at)
+	require.Equal(t, ndgo.SetRDF(`<0x1> <at> "2021-03-04T05:06:07.0000008Z"^^<xs:dateTime> .`+"\n"), set)
+	require.NoError(t, set.Validate())
+}
+
+func TestDateTimeDeletei(t *testing.T) {
+	dg := dgNewClient()
+	defer setupTeardown(dg)()
+	require.NoError(t, dg.Alter(context.Background(), &api.Operation{Schema: `<at>: datetime .
+		<until>: datetime .`}))
+	defer dg.Alter(context.Background(), &api.Operation{DropAttr: "until"})
+	defer dg.Alter(context.Background(), &api.Operation{DropAttr: "at"})
+	txn := ndgo.NewTxnWithoutContext(dg.NewTxn())
+	defer txn.Discard()
+
+	at := time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+	resp, err := txn.Seti(struct {
+		UID string `json:"uid"`
+		event
+	}{UID: "_:e", event: event{At: ndgo.DateTime{Time: at}, Until: ndgo.DateTime{Time: at}}})
+	require.NoError(t, err)
+	uid := resp.Uids["e"]
+
+	// unset Until must not delete until
+	_, err = txn.Deletei(struct {
+		UID string `json:"uid"`
+		event
+	}{UID: uid, event: event{At: ndgo.DateTime{Time: at}}})
+	require.NoError(t, err)
+	resp, err = ndgo.QueryDQL(`{ q(func: uid(` + uid + `)) { at until } }`).Run(txn)
+	require.NoError(t, err)
+	var decoded struct {
+		Q []event `json:"q"`
+	}
+	require.NoError(t, ndgo.Unmarshal(resp.GetJson(), &decoded))
+	require.Len(t, decoded.Q, 1)
+	require.True(t, decoded.Q[0].At.IsZero(), "set DateTime should be deleted")
+	require.True(t, at.Equal(decoded.Q[0].Until.Time), "zero DateTime should not delete the predicate")
+}
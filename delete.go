@@ -0,0 +1,304 @@
+package ndgo
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/dgraph-io/dgo/v210/protos/api"
+)
+
+// --------------------------------------- cascade delete ---------------------------------------
+
+// CascadeOptions configures CascadeDelete
+type CascadeOptions struct {
+	// Edges are predicates to owned nodes, which are deleted along with their owner.
+	// Reverse edges are allowed, i.e. `~owner` deletes nodes which point to the owner with `owner`.
+	Edges []string
+	// Depth is how many levels of Edges are followed, default 1. With Recurse, 0 means unlimited.
+	Depth int
+	// Recurse follows Edges with @recurse(loop: false), instead of a var block per level
+	Recurse bool
+	// Incoming are predicates with @reverse, whose edges from other nodes to deleted nodes are removed.
+	// Predicates without @reverse are rejected, use DeleteIncoming for them, before CascadeDelete.
+	Incoming []string
+	// DryRun only queries what would be deleted
+	DryRun bool
+}
+
+// CascadeResult lists what CascadeDelete deleted, or would delete with DryRun
+type CascadeResult struct {
+	UIDs []string // deleted nodes, sorted
+	// Incoming are removed edges from nodes which are not deleted, pointing to deleted nodes
+	Incoming []NQuad
+}
+
+// CascadeDelete deletes nodes with their owned subgraph, and removes incoming edges pointing to them, in a single upsert request.
+// Unlike Query{}.DeleteNode, it doesn't leave orphaned children or edges to deleted nodes behind.
+func (v *Txn) CascadeDelete(opts CascadeOptions, uids ...string) (res CascadeResult, err error) {
+	if len(uids) == 0 {
+		return res, nil
+	}
+	for _, uid := range uids {
+		if !isUID(uid) {
+			return res, fmt.Errorf("ndgo: cascade delete: invalid uid %q", uid)
+		}
+	}
+	if len(opts.Incoming) > 0 {
+		schema, err := v.Schema()
+		if err != nil {
+			return res, err
+		}
+		for _, pred := range opts.Incoming {
+			if p := schema.Predicate(pred); p == nil || !p.Reverse {
+				return res, fmt.Errorf("ndgo: cascade delete: incoming predicate %s has no @reverse", pred)
+			}
+		}
+	}
+	q, nquads := cascadeDeleteQuery(opts, uids)
+	var resp *api.Response
+	if opts.DryRun {
+		resp, err = v.Query(q)
+	} else {
+		resp, err = v.Do(&api.Request{
+			Query:     q,
+			Mutations: []*api.Mutation{{DelNquads: []byte(nquads)}},
+		})
+	}
+	if err != nil {
+		return res, err
+	}
+	return decodeCascadeResult(resp.GetJson(), opts.Incoming)
+}
+
+// cascadeDeleteQuery builds query, which collects all nodes to delete into var `all`,
+// and N-Quads deleting them and their incoming edges
+func cascadeDeleteQuery(opts CascadeOptions, uids []string) (q, nquads string) {
+	var b strings.Builder
+	b.WriteString("{\n")
+	fmt.Fprintf(&b, "  root as var(func: uid(%s))\n", strings.Join(uids, ", "))
+	vars := []string{"root"}
+	switch {
+	case len(opts.Edges) == 0:
+	case opts.Recurse:
+		args := "loop: false"
+		if opts.Depth > 0 {
+			// recurse depth counts the root level too
+			args = fmt.Sprintf("depth: %d, loop: false", opts.Depth+1)
+		}
+		fmt.Fprintf(&b, "  var(func: uid(root)) @recurse(%s) {\n", args)
+		for i, edge := range opts.Edges {
+			name := fmt.Sprintf("e%d", i)
+			fmt.Fprintf(&b, "    %s as %s\n", name, edge)
+			vars = append(vars, name)
+		}
+		b.WriteString("  }\n")
+	default:
+		depth := opts.Depth
+		if depth <= 0 {
+			depth = 1
+		}
+		level := []string{"root"}
+		for l := 1; l <= depth; l++ {
+			fmt.Fprintf(&b, "  var(func: uid(%s)) {\n", strings.Join(level, ", "))
+			level = nil
+			for i, edge := range opts.Edges {
+				name := fmt.Sprintf("l%d_%d", l, i)
+				fmt.Fprintf(&b, "    %s as %s\n", name, edge)
+				level = append(level, name)
+			}
+			b.WriteString("  }\n")
+			vars = append(vars, level...)
+		}
+	}
+	fmt.Fprintf(&b, "  all as var(func: uid(%s))\n", strings.Join(vars, ", "))
+
+	del := []string{"uid(all) * * ."}
+	b.WriteString("  nodes(func: uid(all)) {\n    uid\n  }\n")
+	del = append(del, writeIncomingQuery(&b, "all", nil, reversePreds(opts.Incoming), !opts.DryRun)...)
+	b.WriteString("}")
+	return b.String(), strings.Join(del, "\n")
+}
+
+func decodeCascadeResult(data []byte, incoming []string) (res CascadeResult, err error) {
+	var decode map[string][]map[string]json.RawMessage
+	if err := json.Unmarshal(data, &decode); err != nil {
+		return res, err
+	}
+	deleted := map[string]bool{}
+	for _, node := range decode["nodes"] {
+		var uid string
+		if err := json.Unmarshal(node["uid"], &uid); err != nil {
+			return res, err
+		}
+		deleted[uid] = true
+		res.UIDs = append(res.UIDs, uid)
+	}
+	sort.Strings(res.UIDs)
+	edges, err := decodeIncoming(decode, reversePreds(incoming))
+	if err != nil {
+		return res, err
+	}
+	for _, nq := range edges {
+		if !deleted[nq.Subject] {
+			res.Incoming = append(res.Incoming, nq)
+		}
+	}
+	return res, nil
+}
+
+// --------------------------------------- incoming edges ---------------------------------------
+
+// DeleteIncoming removes edges from other nodes pointing to uids in a single upsert request, as dgraph doesn't remove them
+// when nodes are deleted with Query{}.DeleteNode. If preds is empty, all @reverse predicates from schema are used.
+// Predicates with @reverse are followed backwards, others are found by scanning has(pred). Returns removed edges.
+func (v *Txn) DeleteIncoming(preds []string, uids ...string) (edges []NQuad, err error) {
+	if len(uids) == 0 {
+		return nil, nil
+	}
+	for _, uid := range uids {
+		if !isUID(uid) {
+			return nil, fmt.Errorf("ndgo: delete incoming: invalid uid %q", uid)
+		}
+	}
+	schema, err := v.Schema()
+	if err != nil {
+		return nil, err
+	}
+	if len(preds) == 0 {
+		preds = schema.ReversePredicates()
+	}
+	if len(preds) == 0 {
+		return nil, nil
+	}
+	incoming := make([]incomingPred, len(preds))
+	for i, pred := range preds {
+		p := schema.Predicate(pred)
+		incoming[i] = incomingPred{pred: pred, reverse: p != nil && p.Reverse}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "{\n  target as var(func: uid(%s))\n", strings.Join(uids, ", "))
+	del := writeIncomingQuery(&b, "target", uids, incoming, true)
+	b.WriteString("}")
+	resp, err := v.Do(&api.Request{
+		Query:     b.String(),
+		Mutations: []*api.Mutation{{DelNquads: []byte(strings.Join(del, "\n"))}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	var decode map[string][]map[string]json.RawMessage
+	if err := json.Unmarshal(resp.GetJson(), &decode); err != nil {
+		return nil, err
+	}
+	return decodeIncoming(decode, incoming)
+}
+
+// incomingPred is a predicate, whose edges pointing to given nodes are removed.
+// Without @reverse, nodes pointing to them are found by scanning has(pred).
+type incomingPred struct {
+	pred    string
+	reverse bool
+}
+
+// writeIncomingQuery writes blocks `incomingN` returning edges of preds pointing to nodes in var target.
+// Scanned predicates filter by uids, which must be the same nodes as target. With mutation, it also
+// writes vars used by returned N-Quads, which delete the edges. Dgraph rejects unused vars, so they are omitted otherwise.
+func writeIncomingQuery(b *strings.Builder, target string, uids []string, preds []incomingPred, mutation bool) (del []string) {
+	for i, p := range preds {
+		scan := fmt.Sprintf("func: has(%s)) @filter(uid_in(%s, [%s]))", p.pred, p.pred, strings.Join(uids, ", "))
+		if mutation {
+			if p.reverse {
+				fmt.Fprintf(b, "  var(func: uid(%s)) {\n    in%d as ~%s\n  }\n", target, i, p.pred)
+			} else {
+				fmt.Fprintf(b, "  in%d as var(%s\n", i, scan)
+			}
+			del = append(del, fmt.Sprintf("uid(in%d) <%s> uid(%s) .", i, p.pred, target))
+		}
+		if p.reverse {
+			fmt.Fprintf(b, "  incoming%d(func: uid(%s)) {\n    uid\n    ~%s {\n      uid\n    }\n  }\n", i, target, p.pred)
+		} else {
+			fmt.Fprintf(b, "  incoming%d(%s {\n    uid\n    %s @filter(uid(%s)) {\n      uid\n    }\n  }\n", i, scan, p.pred, target)
+		}
+	}
+	return del
+}
+
+func reversePreds(preds []string) []incomingPred {
+	res := make([]incomingPred, len(preds))
+	for i, pred := range preds {
+		res[i] = incomingPred{pred: pred, reverse: true}
+	}
+	return res
+}
+
+// decodeIncoming decodes edges returned by writeIncomingQuery blocks
+func decodeIncoming(decode map[string][]map[string]json.RawMessage, preds []incomingPred) (edges []NQuad, err error) {
+	for i, p := range preds {
+		key := p.pred
+		if p.reverse {
+			key = "~" + p.pred
+		}
+		for _, node := range decode[fmt.Sprintf("incoming%d", i)] {
+			var uid string
+			if err := json.Unmarshal(node["uid"], &uid); err != nil {
+				return nil, err
+			}
+			linked, err := linkedUIDs(node[key])
+			if err != nil {
+				return nil, err
+			}
+			for _, l := range linked {
+				if p.reverse {
+					edges = append(edges, NQuad{Subject: l, Predicate: p.pred, ObjectID: uid})
+				} else {
+					edges = append(edges, NQuad{Subject: uid, Predicate: p.pred, ObjectID: l})
+				}
+			}
+		}
+	}
+	return edges, nil
+}
+
+// linkedUIDs decodes uids of nodes linked by an edge, which dgraph returns as object for non-list uid predicates, or list
+func linkedUIDs(raw json.RawMessage) ([]string, error) {
+	type linked struct {
+		UID string `json:"uid"`
+	}
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	if raw[0] == '{' {
+		var l linked
+		if err := json.Unmarshal(raw, &l); err != nil {
+			return nil, err
+		}
+		return []string{l.UID}, nil
+	}
+	var list []linked
+	if err := json.Unmarshal(raw, &list); err != nil {
+		return nil, err
+	}
+	res := make([]string, len(list))
+	for i, l := range list {
+		res[i] = l.UID
+	}
+	return res, nil
+}
+
+// isUID reports whether s is a hex uid, i.e. 0x1a
+func isUID(s string) bool {
+	if len(s) < 3 || s[0] != '0' || (s[1] != 'x' && s[1] != 'X') {
+		return false
+	}
+	for _, c := range s[2:] {
+		if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F') {
+			return false
+		}
+	}
+	return true
+}
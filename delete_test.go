@@ -0,0 +1,87 @@
+package ndgo_test
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	"github.com/dgraph-io/dgo/v210/protos/api"
+	"github.com/ppp225/ndgo/v5"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTxnCascadeDelete(t *testing.T) {
+	dg := dgNewClient()
+	defer setupTeardown(dg)()
+	require.NoError(t, dg.Alter(context.Background(), &api.Operation{Schema: `<testEdge>: [uid] @reverse .`}))
+	txn := ndgo.NewTxnWithoutContext(dg.NewTxn())
+	defer txn.Discard()
+
+	// uid1 owns uid2, uid3 and uid4; uid2 owns grandchild; outsider points to uid2
+	uid1, uid2, uid3, uid4 := populateDBComplex(txn, t)
+	assigned, err := setNode("new", fourthName, fourthAttr).Run(txn)
+	require.NoError(t, err)
+	grandchild := assigned.Uids["new"]
+	assigned, err = setNode("new", fourthName, fourthAttr).Run(txn)
+	require.NoError(t, err)
+	outsider := assigned.Uids["new"]
+	_, err = setEdgeRDF(uid2, grandchild).Run(txn)
+	require.NoError(t, err)
+	_, err = setEdgeRDF(outsider, uid2).Run(txn)
+	require.NoError(t, err)
+
+	deleted := []string{uid1, uid2, uid3, uid4}
+	sort.Strings(deleted)
+	opts := ndgo.CascadeOptions{Edges: []string{predicateEdge}, Incoming: []string{predicateEdge}, DryRun: true}
+	res, err := txn.CascadeDelete(opts, uid1)
+	require.NoError(t, err)
+	require.Equal(t, deleted, res.UIDs)
+	require.Equal(t, []ndgo.NQuad{{Subject: outsider, Predicate: predicateEdge, ObjectID: uid2}}, res.Incoming)
+
+	opts.DryRun = false
+	res, err = txn.CascadeDelete(opts, uid1)
+	require.NoError(t, err)
+	require.Equal(t, deleted, res.UIDs)
+
+	res, err = txn.CascadeDelete(ndgo.CascadeOptions{Edges: []string{predicateEdge}, DryRun: true}, outsider, grandchild)
+	require.NoError(t, err)
+	require.Len(t, res.UIDs, 2, "outsider edge should be removed, and grandchild should be kept")
+
+	_, err = txn.CascadeDelete(opts, "_:new")
+	require.EqualError(t, err, `ndgo: cascade delete: invalid uid "_:new"`)
+	_, err = txn.CascadeDelete(ndgo.CascadeOptions{Incoming: []string{predicateName}}, outsider)
+	require.EqualError(t, err, "ndgo: cascade delete: incoming predicate testName has no @reverse")
+}
+
+func TestTxnDeleteIncoming(t *testing.T) {
+	dg := dgNewClient()
+	defer setupTeardown(dg)()
+	txn := ndgo.NewTxnWithoutContext(dg.NewTxn())
+	defer txn.Discard()
+	uid1, uid2, uid3, _ := populateDBComplex(txn, t)
+
+	// testEdge has no @reverse, so it's scanned
+	edges, err := txn.DeleteIncoming([]string{predicateEdge}, uid2)
+	require.NoError(t, err)
+	require.Equal(t, []ndgo.NQuad{{Subject: uid1, Predicate: predicateEdge, ObjectID: uid2}}, edges)
+	require.NoError(t, txn.Commit())
+
+	require.NoError(t, dg.Alter(context.Background(), &api.Operation{Schema: `<testEdge>: [uid] @reverse .`}))
+	txn = ndgo.NewTxnWithoutContext(dg.NewTxn())
+	defer txn.Discard()
+	edges, err = txn.DeleteIncoming(nil, uid2, uid3)
+	require.NoError(t, err)
+	require.Equal(t, []ndgo.NQuad{{Subject: uid1, Predicate: predicateEdge, ObjectID: uid3}}, edges, "@reverse predicates should be read from schema")
+
+	require.NoError(t, txn.Commit())
+
+	// non-list uid predicates are returned as object, not list
+	require.NoError(t, dg.Alter(context.Background(), &api.Operation{Schema: `<testOwner>: uid .`}))
+	txn = ndgo.NewTxnWithoutContext(dg.NewTxn())
+	defer txn.Discard()
+	_, err = ndgo.Query{}.SetEdge(uid1, "testOwner", uid2).Run(txn)
+	require.NoError(t, err)
+	edges, err = txn.DeleteIncoming([]string{"testOwner"}, uid2)
+	require.NoError(t, err)
+	require.Equal(t, []ndgo.NQuad{{Subject: uid1, Predicate: "testOwner", ObjectID: uid2}}, edges)
+}
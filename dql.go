@@ -0,0 +1,1426 @@
+package ndgo
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// --------------------------------------- ast ---------------------------------------
+
+// DQLDocument is a parsed DQL query. Queries joined with QueryDQL.Join are parsed into a single document.
+type DQLDocument struct {
+	Name      string         // query name, from `query name($a: string)`
+	Vars      []DQLVarDecl   // query variable declarations
+	Blocks    []*DQLBlock    // query blocks, including var blocks
+	Fragments []*DQLFragment // fragment definitions
+	Comments  int            // number of `#` comments, which are not preserved when printing
+}
+
+// DQLVarDecl is a query variable declaration, i.e. `$name: string = "default"`
+type DQLVarDecl struct {
+	Name    string // with `$` prefix
+	Type    string
+	Default string // raw default value, including quotes, empty if none
+	Pos     DQLPos
+}
+
+// DQLBlock is a query block, i.e. `a as q(func: eq(name, "x"), first: 1) @filter(has(age)) { uid }`
+type DQLBlock struct {
+	Name       string // block name, `var` for var blocks
+	Var        string // variable assigned to whole block, i.e. `a` in `a as var(...)`
+	Args       []DQLArg
+	Directives []DQLDirective
+	Children   []*DQLField
+	Pos        DQLPos
+}
+
+// DQLFragment is a fragment definition, i.e. `fragment f { name }`
+type DQLFragment struct {
+	Name     string
+	Children []*DQLField
+	Pos      DQLPos
+}
+
+// DQLField is a predicate, aggregation or function in a selection set
+type DQLField struct {
+	Alias string // `alias: pred`
+	Var   string // `v as pred`
+	// Name is predicate name (possibly `~reverse`), `uid`, or `...fragment` spread.
+	// For function fields, i.e. count(pred), val(v), expand(_all_), Name is empty and Func is set.
+	Name       string
+	Func       *DQLExpr
+	Lang       string // language list, i.e. `en:de:.` in `name@en:de:.`
+	Args       []DQLArg
+	Directives []DQLDirective
+	Children   []*DQLField
+	Pos        DQLPos
+}
+
+// DQLDirective is a directive, i.e. `@filter(has(name))`, `@facets(since)` or `@cascade`
+type DQLDirective struct {
+	Name string
+	Args []DQLArg
+	Pos  DQLPos
+}
+
+// DQLArg is a (possibly named) argument, i.e. `first: 10` or unnamed `has(name)` in @filter
+type DQLArg struct {
+	Name  string
+	Var   string // variable assigned to facet, i.e. `w` in `@facets(w as weight)`
+	Value *DQLExpr
+}
+
+// DQLExprKind is the kind of DQLExpr
+type DQLExprKind int
+
+// DQLExpr kinds
+const (
+	DQLExprValue DQLExprKind = iota // identifier, number, quoted string, /regex/flags or $var in Value
+	DQLExprFunc                     // function call Func(Args...)
+	DQLExprAnd                      // Args[0] AND Args[1] ...
+	DQLExprOr                       // Args[0] OR Args[1] ...
+	DQLExprNot                      // NOT Args[0]
+	DQLExprList                     // [Args...]
+	DQLExprMath                     // math expression in Value, i.e. `a + b`
+)
+
+// DQLExpr is a value, function call, list or boolean expression
+type DQLExpr struct {
+	Kind  DQLExprKind
+	Value string
+	Func  string
+	Args  []*DQLExpr
+	Pos   DQLPos
+}
+
+// DQLPos is a position in DQL query
+type DQLPos struct {
+	Line   int
+	Column int
+}
+
+func (v DQLPos) String() string {
+	return fmt.Sprintf("%d:%d", v.Line, v.Column)
+}
+
+// DQLError is returned, when DQL can't be parsed
+type DQLError struct {
+	DQLPos
+	Msg string
+}
+
+func (v *DQLError) Error() string {
+	return fmt.Sprintf("ndgo: dql %s: %s", v.DQLPos, v.Msg)
+}
+
+// --------------------------------------- parse ---------------------------------------
+
+// Parse parses the query into DQLDocument
+func (v QueryDQL) Parse() (*DQLDocument, error) {
+	return ParseDQL(string(v))
+}
+
+// ParseDQL parses DQL query. It also accepts queries joined by QueryDQL.Join, optionally wrapped in brackets.
+// Errors are of type *DQLError, with line and column of the problem.
+func ParseDQL(q string) (doc *DQLDocument, err error) {
+	p := dqlParser{lex: dqlLexer{s: q, line: 1, col: 1}}
+	defer func() {
+		if r := recover(); r != nil {
+			if perr, ok := r.(*DQLError); ok {
+				doc, err = nil, perr
+				return
+			}
+			panic(r)
+		}
+	}()
+	return p.document(), nil
+}
+
+type dqlParser struct {
+	lex dqlLexer
+}
+
+func (v *dqlParser) fail(pos DQLPos, format string, args ...interface{}) {
+	panic(&DQLError{DQLPos: pos, Msg: fmt.Sprintf(format, args...)})
+}
+
+func (v *dqlParser) expect(punct string) dqlToken {
+	t := v.lex.next()
+	if t.kind != dqlPunct || t.val != punct {
+		v.fail(t.pos, "expected '%s', got %s", punct, t)
+	}
+	return t
+}
+
+func (v *dqlParser) isPunct(punct string) bool {
+	t := v.lex.peek()
+	return t.kind == dqlPunct && t.val == punct
+}
+
+func (v *dqlParser) skipPunct(punct string) bool {
+	if v.isPunct(punct) {
+		v.lex.next()
+		return true
+	}
+	return false
+}
+
+func (v *dqlParser) ident() dqlToken {
+	t := v.lex.next()
+	if t.kind != dqlIdent {
+		v.fail(t.pos, "expected name, got %s", t)
+	}
+	return t
+}
+
+// document := '['? part (','? part)* ']'?
+func (v *dqlParser) document() *DQLDocument {
+	doc := &DQLDocument{}
+	bracketed := v.skipPunct("[")
+	for {
+		v.part(doc)
+		v.skipPunct(",")
+		if t := v.lex.peek(); t.kind == dqlEOF || bracketed && v.isPunct("]") {
+			break
+		}
+	}
+	if bracketed {
+		v.expect("]")
+	}
+	if t := v.lex.next(); t.kind != dqlEOF {
+		v.fail(t.pos, "unexpected %s after query", t)
+	}
+	if len(doc.Blocks) == 0 && len(doc.Fragments) == 0 {
+		v.fail(DQLPos{Line: 1, Column: 1}, "query has no blocks")
+	}
+	doc.Comments = v.lex.comments
+	return doc
+}
+
+// part := 'fragment' name selection | 'schema' ... | ('query' name? vars?)? '{' block* '}'
+func (v *dqlParser) part(doc *DQLDocument) {
+	t := v.lex.peek()
+	if t.kind == dqlIdent && t.val == "fragment" {
+		v.lex.next()
+		name := v.ident()
+		doc.Fragments = append(doc.Fragments, &DQLFragment{Name: name.val, Children: v.selection(), Pos: t.pos})
+		return
+	}
+	if t.kind == dqlIdent && t.val == "schema" {
+		doc.Blocks = append(doc.Blocks, v.block())
+		return
+	}
+	if t.kind == dqlIdent && t.val == "query" {
+		v.lex.next()
+		if v.lex.peek().kind == dqlIdent {
+			doc.Name = v.lex.next().val
+		}
+		if v.skipPunct("(") {
+			for !v.skipPunct(")") {
+				doc.Vars = append(doc.Vars, v.varDecl())
+				v.skipPunct(",")
+			}
+		}
+	}
+	v.expect("{")
+	for !v.skipPunct("}") {
+		if v.lex.peek().kind == dqlEOF {
+			v.fail(v.lex.peek().pos, "unclosed '{'")
+		}
+		doc.Blocks = append(doc.Blocks, v.block())
+		v.skipPunct(",")
+	}
+}
+
+// varDecl := $name ':' type ('=' value)?
+func (v *dqlParser) varDecl() DQLVarDecl {
+	t := v.lex.next()
+	if t.kind != dqlVar {
+		v.fail(t.pos, "query variable must start with '$', got %s", t)
+	}
+	d := DQLVarDecl{Name: t.val, Pos: t.pos}
+	v.expect(":")
+	d.Type = v.ident().val
+	if v.skipPunct("!") {
+		d.Type += "!"
+	}
+	if v.skipPunct("=") {
+		val := v.lex.next()
+		if val.kind != dqlString && val.kind != dqlIdent {
+			v.fail(val.pos, "invalid default value %s", val)
+		}
+		d.Default = val.val
+	}
+	return d
+}
+
+// block := (var 'as')? name '(' args ')' directives ('{' fields '}')?
+func (v *dqlParser) block() *DQLBlock {
+	b := &DQLBlock{}
+	t := v.ident()
+	b.Pos = t.pos
+	if next := v.lex.peek(); next.kind == dqlIdent && next.val == "as" {
+		v.lex.next()
+		b.Var = t.val
+		t = v.ident()
+	}
+	b.Name = t.val
+	if v.isPunct("(") {
+		b.Args = v.args(false)
+	}
+	b.Directives = v.directives()
+	if v.isPunct("{") {
+		b.Children = v.selection()
+	}
+	return b
+}
+
+// selection := '{' field* '}'
+func (v *dqlParser) selection() (fields []*DQLField) {
+	v.expect("{")
+	for !v.skipPunct("}") {
+		if v.lex.peek().kind == dqlEOF {
+			v.fail(v.lex.peek().pos, "unclosed '{'")
+		}
+		fields = append(fields, v.field())
+		v.skipPunct(",")
+	}
+	return fields
+}
+
+// dqlFieldFuncs are functions, which can be used in place of a predicate in selection
+var dqlFieldFuncs = map[string]bool{
+	"count": true, "val": true, "expand": true, "math": true, "checkpwd": true,
+	"min": true, "max": true, "sum": true, "avg": true, "uid": true,
+}
+
+// field := (alias ':')? (var 'as')? (name ('@' lang)? | func) args? directives selection?
+func (v *dqlParser) field() *DQLField {
+	f := &DQLField{}
+	if v.isPunct("...") {
+		t := v.lex.next()
+		f.Name, f.Pos = "..."+v.ident().val, t.pos
+		return f
+	}
+	t := v.ident()
+	f.Pos = t.pos
+	if v.skipPunct(":") {
+		f.Alias = t.val
+		t = v.ident()
+	}
+	if next := v.lex.peek(); next.kind == dqlIdent && next.val == "as" {
+		v.lex.next()
+		f.Var = t.val
+		t = v.ident()
+	}
+
+	if dqlFieldFuncs[t.val] && v.isPunct("(") {
+		f.Func = v.funcCall(t)
+	} else {
+		f.Name = t.val
+		if v.isPunct("@") && !v.lex.atDirective() {
+			v.lex.next()
+			f.Lang = v.lex.lang()
+			if f.Lang == "" {
+				v.fail(t.pos, "empty language list of %s", f.Name)
+			}
+		}
+		if v.isPunct("(") {
+			f.Args = v.args(false)
+		}
+	}
+	f.Directives = v.directives()
+	if v.isPunct("{") {
+		f.Children = v.selection()
+	}
+	return f
+}
+
+// directives := ('@' name ('(' args ')')?)*
+func (v *dqlParser) directives() (directives []DQLDirective) {
+	for v.isPunct("@") {
+		at := v.lex.next()
+		d := DQLDirective{Name: v.ident().val, Pos: at.pos}
+		if v.isPunct("(") {
+			d.Args = v.args(d.Name == "filter")
+		}
+		directives = append(directives, d)
+	}
+	return directives
+}
+
+// args := '(' (name ':')? (var 'as')? expr (',' (name ':')? (var 'as')? expr)* ')'
+// Empty `()` returns non-nil empty args, so it's printed back, i.e. `q() { ... }` of aggregation blocks.
+func (v *dqlParser) args(boolean bool) (args []DQLArg) {
+	v.expect("(")
+	args = []DQLArg{}
+	for !v.skipPunct(")") {
+		arg := DQLArg{}
+		if t := v.lex.peek(); t.kind == dqlIdent && v.lex.peekN(2).kind == dqlPunct && v.lex.peekN(2).val == ":" {
+			arg.Name = v.lex.next().val
+			v.lex.next()
+		}
+		if t := v.lex.peek(); t.kind == dqlIdent && v.lex.peekN(2).kind == dqlIdent && v.lex.peekN(2).val == "as" {
+			arg.Var = v.lex.next().val
+			v.lex.next()
+		}
+		if boolean {
+			arg.Value = v.or()
+		} else {
+			arg.Value = v.value()
+		}
+		args = append(args, arg)
+		if !v.skipPunct(",") && !v.isPunct(")") {
+			t := v.lex.peek()
+			v.fail(t.pos, "expected ',' or ')', got %s", t)
+		}
+	}
+	return args
+}
+
+// or := and ('OR' and)*
+func (v *dqlParser) or() *DQLExpr {
+	e := v.and()
+	for v.isKeyword("or") {
+		v.lex.next()
+		if e.Kind != DQLExprOr {
+			e = &DQLExpr{Kind: DQLExprOr, Args: []*DQLExpr{e}, Pos: e.Pos}
+		}
+		e.Args = append(e.Args, v.and())
+	}
+	return e
+}
+
+// and := not ('AND' not)*
+func (v *dqlParser) and() *DQLExpr {
+	e := v.not()
+	for v.isKeyword("and") {
+		v.lex.next()
+		if e.Kind != DQLExprAnd {
+			e = &DQLExpr{Kind: DQLExprAnd, Args: []*DQLExpr{e}, Pos: e.Pos}
+		}
+		e.Args = append(e.Args, v.not())
+	}
+	return e
+}
+
+// not := 'NOT' not | '(' or ')' | value
+func (v *dqlParser) not() *DQLExpr {
+	if v.isKeyword("not") {
+		t := v.lex.next()
+		return &DQLExpr{Kind: DQLExprNot, Args: []*DQLExpr{v.not()}, Pos: t.pos}
+	}
+	if v.skipPunct("(") {
+		e := v.or()
+		v.expect(")")
+		if e.Kind == DQLExprAnd || e.Kind == DQLExprOr {
+			// keep grouping, so formatting preserves precedence
+			return &DQLExpr{Kind: e.Kind, Args: e.Args, Pos: e.Pos, Value: "()"}
+		}
+		return e
+	}
+	return v.value()
+}
+
+func (v *dqlParser) isKeyword(kw string) bool {
+	t := v.lex.peek()
+	return t.kind == dqlIdent && strings.EqualFold(t.val, kw)
+}
+
+// value := func | '[' value (',' value)* ']' | ident | string | regex | $var
+func (v *dqlParser) value() *DQLExpr {
+	t := v.lex.next()
+	switch {
+	case t.kind == dqlPunct && t.val == "[":
+		e := &DQLExpr{Kind: DQLExprList, Pos: t.pos}
+		for !v.skipPunct("]") {
+			if v.lex.peek().kind == dqlEOF {
+				v.fail(e.Pos, "unclosed '['")
+			}
+			e.Args = append(e.Args, v.value())
+			v.skipPunct(",") // commas in lists are optional
+		}
+		return e
+	case t.kind == dqlIdent && v.isPunct("("):
+		return v.funcCall(t)
+	case t.kind == dqlIdent:
+		if lang := v.lex.attachedLang(); lang != "" {
+			t.val += "@" + lang
+		}
+		return &DQLExpr{Kind: DQLExprValue, Value: t.val, Pos: t.pos}
+	case t.kind == dqlString || t.kind == dqlRegex || t.kind == dqlVar:
+		return &DQLExpr{Kind: DQLExprValue, Value: t.val, Pos: t.pos}
+	}
+	v.fail(t.pos, "expected value, got %s", t)
+	return nil
+}
+
+// funcCall := name '(' value (',' value)* ')', math contents are kept raw
+func (v *dqlParser) funcCall(name dqlToken) *DQLExpr {
+	if name.val == "math" {
+		open := v.expect("(")
+		raw, ok := v.lex.until(')')
+		if !ok {
+			v.fail(open.pos, "unclosed math(")
+		}
+		return &DQLExpr{Kind: DQLExprFunc, Func: "math", Pos: name.pos, Args: []*DQLExpr{
+			{Kind: DQLExprMath, Value: strings.Join(strings.Fields(raw), " "), Pos: open.pos},
+		}}
+	}
+	e := &DQLExpr{Kind: DQLExprFunc, Func: name.val, Pos: name.pos}
+	v.expect("(")
+	for !v.skipPunct(")") {
+		e.Args = append(e.Args, v.value())
+		if !v.skipPunct(",") && !v.isPunct(")") {
+			t := v.lex.peek()
+			v.fail(t.pos, "expected ',' or ')' in %s(), got %s", name.val, t)
+		}
+	}
+	return e
+}
+
+// --------------------------------------- lexer ---------------------------------------
+
+type dqlTokenKind int
+
+const (
+	dqlEOF dqlTokenKind = iota
+	dqlIdent
+	dqlString
+	dqlRegex
+	dqlVar
+	dqlPunct
+)
+
+type dqlToken struct {
+	kind dqlTokenKind
+	val  string
+	pos  DQLPos
+}
+
+func (v dqlToken) String() string {
+	if v.kind == dqlEOF {
+		return "end of query"
+	}
+	return fmt.Sprintf("%q", v.val)
+}
+
+type dqlLexer struct {
+	s         string
+	pos       int
+	line, col int
+	comments  int
+}
+
+func isDQLIdentChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' ||
+		c == '_' || c == '.' || c == '~' || c == '-' || c == '+' || c >= 0x80
+}
+
+func (v *dqlLexer) advance(n int) {
+	for i := 0; i < n && v.pos < len(v.s); i++ {
+		if v.s[v.pos] == '\n' {
+			v.line++
+			v.col = 1
+		} else {
+			v.col++
+		}
+		v.pos++
+	}
+}
+
+func (v *dqlLexer) skipSpace() {
+	for v.pos < len(v.s) {
+		switch c := v.s[v.pos]; {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			v.advance(1)
+		case c == '#':
+			v.comments++
+			for v.pos < len(v.s) && v.s[v.pos] != '\n' {
+				v.advance(1)
+			}
+		default:
+			return
+		}
+	}
+}
+
+func (v *dqlLexer) next() dqlToken {
+	v.skipSpace()
+	pos := DQLPos{Line: v.line, Column: v.col}
+	if v.pos >= len(v.s) {
+		return dqlToken{kind: dqlEOF, pos: pos}
+	}
+	c := v.s[v.pos]
+	start := v.pos
+	switch {
+	case c == '"':
+		v.advance(1)
+		for v.pos < len(v.s) && v.s[v.pos] != '"' {
+			if v.s[v.pos] == '\\' {
+				v.advance(1)
+			}
+			v.advance(1)
+		}
+		if v.pos >= len(v.s) {
+			panic(&DQLError{DQLPos: pos, Msg: "unclosed string"})
+		}
+		v.advance(1)
+		return dqlToken{kind: dqlString, val: v.s[start:v.pos], pos: pos}
+	case c == '/':
+		// regex literal, i.e. `/^Steven.*$/i`
+		v.advance(1)
+		for v.pos < len(v.s) && v.s[v.pos] != '/' && v.s[v.pos] != '\n' {
+			if v.s[v.pos] == '\\' {
+				v.advance(1)
+			}
+			v.advance(1)
+		}
+		if v.pos >= len(v.s) || v.s[v.pos] != '/' {
+			panic(&DQLError{DQLPos: pos, Msg: "unclosed regex"})
+		}
+		v.advance(1)
+		for v.pos < len(v.s) && v.s[v.pos] >= 'a' && v.s[v.pos] <= 'z' {
+			v.advance(1)
+		}
+		return dqlToken{kind: dqlRegex, val: v.s[start:v.pos], pos: pos}
+	case c == '<':
+		end := strings.IndexByte(v.s[v.pos:], '>')
+		if end < 0 {
+			panic(&DQLError{DQLPos: pos, Msg: "unclosed '<'"})
+		}
+		v.advance(end + 1)
+		return dqlToken{kind: dqlIdent, val: v.s[start:v.pos], pos: pos}
+	case c == '$':
+		v.advance(1)
+		for v.pos < len(v.s) && isDQLIdentChar(v.s[v.pos]) {
+			v.advance(1)
+		}
+		return dqlToken{kind: dqlVar, val: v.s[start:v.pos], pos: pos}
+	case strings.HasPrefix(v.s[v.pos:], "..."):
+		v.advance(3)
+		return dqlToken{kind: dqlPunct, val: "...", pos: pos}
+	case isDQLIdentChar(c) || c == '*':
+		for v.pos < len(v.s) && (isDQLIdentChar(v.s[v.pos]) || v.s[v.pos] == '*') {
+			v.advance(1)
+		}
+		return dqlToken{kind: dqlIdent, val: v.s[start:v.pos], pos: pos}
+	}
+	v.advance(1)
+	return dqlToken{kind: dqlPunct, val: string(c), pos: pos}
+}
+
+func (v *dqlLexer) peek() dqlToken {
+	return v.peekN(1)
+}
+
+// peekN returns n-th next token, without consuming it
+func (v *dqlLexer) peekN(n int) (t dqlToken) {
+	saved := *v
+	for i := 0; i < n; i++ {
+		t = v.next()
+	}
+	*v = saved
+	return t
+}
+
+// atDirective reports whether `@` at current position starts a directive rather than language list
+func (v *dqlLexer) atDirective() bool {
+	saved := *v
+	defer func() { *v = saved }()
+	v.next() // @
+	t := v.next()
+	if t.kind != dqlIdent {
+		return false
+	}
+	switch t.val {
+	case "filter", "facets", "cascade", "normalize", "recurse", "groupby", "ignorereflex", "include", "skip":
+		return true
+	}
+	return false
+}
+
+// lang reads raw language list after `@`, i.e. `en:de:.` or `*`
+func (v *dqlLexer) lang() string {
+	start := v.pos
+	for v.pos < len(v.s) {
+		c := v.s[v.pos]
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == ':' || c == '.' || c == '*' || c == '-' || c == '_') {
+			break
+		}
+		v.advance(1)
+	}
+	return v.s[start:v.pos]
+}
+
+// attachedLang reads language list directly following a value, i.e. `en` in `eq(name@en, "x")`
+func (v *dqlLexer) attachedLang() string {
+	if v.pos >= len(v.s) || v.s[v.pos] != '@' {
+		return ""
+	}
+	v.advance(1)
+	return v.lang()
+}
+
+// until reads raw text until closing char, respecting nested parentheses, and consumes the closing char
+func (v *dqlLexer) until(closing byte) (string, bool) {
+	start, depth := v.pos, 0
+	for v.pos < len(v.s) {
+		c := v.s[v.pos]
+		switch {
+		case c == '(':
+			depth++
+		case c == closing && depth == 0:
+			raw := v.s[start:v.pos]
+			v.advance(1)
+			return raw, true
+		case c == ')':
+			depth--
+		}
+		v.advance(1)
+	}
+	return "", false
+}
+
+// --------------------------------------- inspect ---------------------------------------
+
+// BlockNames returns names of all non-var query blocks, in order
+func (v *DQLDocument) BlockNames() (names []string) {
+	for _, b := range v.Blocks {
+		if b.Name != "var" {
+			names = append(names, b.Name)
+		}
+	}
+	return names
+}
+
+// Variables returns sorted names of defined (`v as ...`) and used (`uid(v)`, `val(v)`) query variables
+func (v *DQLDocument) Variables() (defined, used []string) {
+	defs, uses := v.variables()
+	for name := range defs {
+		defined = append(defined, name)
+	}
+	for name := range uses {
+		used = append(used, name)
+	}
+	sort.Strings(defined)
+	sort.Strings(used)
+	return defined, used
+}
+
+// Predicates returns sorted, unique predicates referenced in selections, functions and ordering.
+// Reverse predicates are returned without `~`.
+func (v *DQLDocument) Predicates() []string {
+	preds := map[string]bool{}
+	add := func(name string) {
+		if idx := strings.IndexByte(name, '@'); idx > 0 {
+			name = name[:idx]
+		}
+		name = strings.TrimPrefix(strings.Trim(name, "<>"), "~")
+		if name != "" && name != "uid" && name != "_all_" && name != "_predicate_" {
+			preds[name] = true
+		}
+	}
+	var fromArgs func(args []DQLArg)
+	var fromExpr func(e *DQLExpr)
+	fromExpr = func(e *DQLExpr) {
+		if e == nil {
+			return
+		}
+		switch e.Kind {
+		case DQLExprFunc:
+			switch e.Func {
+			case "uid", "val", "math", "type", "shortest", "recurse", "checkpwd", "expand":
+			default:
+				// first argument of other functions is a predicate, i.e. eq(name, "x") or count(friend)
+				if len(e.Args) > 0 && e.Args[0].Kind == DQLExprValue && !strings.HasPrefix(e.Args[0].Value, "$") && !strings.HasPrefix(e.Args[0].Value, `"`) {
+					add(e.Args[0].Value)
+				}
+			}
+			for _, a := range e.Args {
+				fromExpr(a)
+			}
+		case DQLExprAnd, DQLExprOr, DQLExprNot, DQLExprList:
+			for _, a := range e.Args {
+				fromExpr(a)
+			}
+		}
+	}
+	fromArgs = func(args []DQLArg) {
+		for _, a := range args {
+			switch a.Name {
+			case "orderasc", "orderdesc":
+				if a.Value.Kind == DQLExprValue {
+					add(a.Value.Value)
+				}
+			}
+			fromExpr(a.Value)
+		}
+	}
+	var fromFields func(fields []*DQLField)
+	fromFields = func(fields []*DQLField) {
+		for _, f := range fields {
+			if f.Func != nil {
+				fromExpr(f.Func)
+			} else if !strings.HasPrefix(f.Name, "...") {
+				add(f.Name)
+			}
+			fromArgs(f.Args)
+			for _, d := range f.Directives {
+				if d.Name != "facets" {
+					fromArgs(d.Args)
+				}
+			}
+			fromFields(f.Children)
+		}
+	}
+	for _, b := range v.Blocks {
+		fromArgs(b.Args)
+		for _, d := range b.Directives {
+			fromArgs(d.Args)
+		}
+		fromFields(b.Children)
+	}
+	for _, f := range v.Fragments {
+		fromFields(f.Children)
+	}
+
+	res := make([]string, 0, len(preds))
+	for p := range preds {
+		res = append(res, p)
+	}
+	sort.Strings(res)
+	return res
+}
+
+// variables collects positions of query variable definitions and usages
+func (v *DQLDocument) variables() (defs, uses map[string]DQLPos) {
+	defs, uses = map[string]DQLPos{}, map[string]DQLPos{}
+	var fromExpr func(e *DQLExpr)
+	fromExpr = func(e *DQLExpr) {
+		if e == nil {
+			return
+		}
+		if e.Kind == DQLExprFunc && (e.Func == "uid" || e.Func == "val") {
+			for _, a := range e.Args {
+				if a.Kind == DQLExprValue && isDQLVarName(a.Value) {
+					if _, ok := uses[a.Value]; !ok {
+						uses[a.Value] = a.Pos
+					}
+				}
+			}
+		}
+		if e.Kind == DQLExprMath {
+			for _, name := range strings.FieldsFunc(e.Value, func(r rune) bool { return r < 0x80 && !isDQLMathIdentChar(byte(r)) }) {
+				if isDQLVarName(name) && !dqlMathFuncs[name] {
+					if _, ok := uses[name]; !ok {
+						uses[name] = e.Pos
+					}
+				}
+			}
+		}
+		for _, a := range e.Args {
+			fromExpr(a)
+		}
+	}
+	fromArgs := func(args []DQLArg) {
+		for _, a := range args {
+			fromExpr(a.Value)
+		}
+	}
+	var fromFields func(fields []*DQLField)
+	fromFields = func(fields []*DQLField) {
+		for _, f := range fields {
+			if f.Var != "" {
+				defs[f.Var] = f.Pos
+			}
+			fromExpr(f.Func)
+			fromArgs(f.Args)
+			for _, d := range f.Directives {
+				for _, a := range d.Args {
+					if a.Var != "" {
+						defs[a.Var] = d.Pos
+					}
+				}
+				fromArgs(d.Args)
+			}
+			fromFields(f.Children)
+		}
+	}
+	for _, b := range v.Blocks {
+		if b.Var != "" {
+			defs[b.Var] = b.Pos
+		}
+		fromArgs(b.Args)
+		for _, d := range b.Directives {
+			fromArgs(d.Args)
+		}
+		fromFields(b.Children)
+	}
+	for _, f := range v.Fragments {
+		fromFields(f.Children)
+	}
+	return defs, uses
+}
+
+// dqlMathFuncs are functions and constants, which can appear in math expressions
+var dqlMathFuncs = map[string]bool{
+	"exp": true, "ln": true, "sqrt": true, "floor": true, "ceil": true, "since": true, "pow": true, "logbase": true,
+	"cond": true, "min": true, "max": true, "dot": true, "true": true, "false": true,
+}
+
+// isDQLVarName reports whether s looks like a query variable, not a uid, number or string
+func isDQLVarName(s string) bool {
+	if s == "" || s[0] >= '0' && s[0] <= '9' || s[0] == '"' || s[0] == '$' || s[0] == '<' || s[0] == '-' {
+		return false
+	}
+	return true
+}
+
+// --------------------------------------- lint ---------------------------------------
+
+// DQLIssue is a problem found by Lint
+type DQLIssue struct {
+	Pos DQLPos
+	Msg string
+}
+
+func (v DQLIssue) String() string {
+	return fmt.Sprintf("%s: %s", v.Pos, v.Msg)
+}
+
+// Lint parses the query and reports common mistakes. Parse errors are returned as the only issue.
+func (v QueryDQL) Lint() []DQLIssue {
+	return LintDQL(string(v), nil)
+}
+
+// LintDQL parses the query and reports common mistakes: duplicate block names (i.e. when using Join),
+// undefined or unused query variables, and `$` variables not matching the query declaration.
+// Pass vars, which will be used with QueryWithVars, to check them too, or nil to skip.
+func LintDQL(q string, vars map[string]string) []DQLIssue {
+	doc, err := ParseDQL(q)
+	if err != nil {
+		if derr, ok := err.(*DQLError); ok {
+			return []DQLIssue{{Pos: derr.DQLPos, Msg: derr.Msg}}
+		}
+		return []DQLIssue{{Msg: err.Error()}}
+	}
+	return doc.Lint(vars)
+}
+
+// Lint reports common mistakes in parsed query. See LintDQL.
+func (v *DQLDocument) Lint(vars map[string]string) (issues []DQLIssue) {
+	seen := map[string]DQLPos{}
+	for _, b := range v.Blocks {
+		if b.Name == "var" {
+			continue
+		}
+		if first, ok := seen[b.Name]; ok {
+			issues = append(issues, DQLIssue{Pos: b.Pos, Msg: fmt.Sprintf("duplicate block name %q, first defined at %s", b.Name, first)})
+			continue
+		}
+		seen[b.Name] = b.Pos
+	}
+
+	defs, uses := v.variables()
+	for _, name := range sortedPosKeys(uses) {
+		if _, ok := defs[name]; !ok {
+			issues = append(issues, DQLIssue{Pos: uses[name], Msg: fmt.Sprintf("variable %q is used, but not defined", name)})
+		}
+	}
+	for _, name := range sortedPosKeys(defs) {
+		if _, ok := uses[name]; !ok {
+			issues = append(issues, DQLIssue{Pos: defs[name], Msg: fmt.Sprintf("variable %q is defined, but not used", name)})
+		}
+	}
+
+	declared := map[string]bool{}
+	for _, d := range v.Vars {
+		declared[d.Name] = true
+	}
+	for _, use := range v.queryVarUses() {
+		if !declared[use.Value] {
+			issues = append(issues, DQLIssue{Pos: use.Pos, Msg: fmt.Sprintf("query variable %s is not declared in query header", use.Value)})
+		}
+	}
+	if vars != nil {
+		keys := make([]string, 0, len(vars))
+		for key := range vars {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			switch {
+			case !strings.HasPrefix(key, "$"):
+				issues = append(issues, DQLIssue{Msg: fmt.Sprintf("vars key %q must start with '$'", key)})
+			case !declared[key]:
+				issues = append(issues, DQLIssue{Msg: fmt.Sprintf("vars key %q is not declared in query header", key)})
+			}
+		}
+		for _, d := range v.Vars {
+			if _, ok := vars[d.Name]; !ok && d.Default == "" {
+				issues = append(issues, DQLIssue{Pos: d.Pos, Msg: fmt.Sprintf("query variable %s has no value and no default", d.Name)})
+			}
+		}
+	}
+	return issues
+}
+
+// queryVarUses returns all `$var` values used in the query body
+func (v *DQLDocument) queryVarUses() (uses []*DQLExpr) {
+	var fromExpr func(e *DQLExpr)
+	fromExpr = func(e *DQLExpr) {
+		if e == nil {
+			return
+		}
+		if e.Kind == DQLExprValue && strings.HasPrefix(e.Value, "$") {
+			uses = append(uses, e)
+		}
+		for _, a := range e.Args {
+			fromExpr(a)
+		}
+	}
+	v.walkExprs(fromExpr)
+	return uses
+}
+
+// walkExprs calls fx with every top level expression of args and directives in the document
+func (v *DQLDocument) walkExprs(fx func(e *DQLExpr)) {
+	fromArgs := func(args []DQLArg) {
+		for _, a := range args {
+			fx(a.Value)
+		}
+	}
+	var fromFields func(fields []*DQLField)
+	fromFields = func(fields []*DQLField) {
+		for _, f := range fields {
+			fx(f.Func)
+			fromArgs(f.Args)
+			for _, d := range f.Directives {
+				fromArgs(d.Args)
+			}
+			fromFields(f.Children)
+		}
+	}
+	for _, b := range v.Blocks {
+		fromArgs(b.Args)
+		for _, d := range b.Directives {
+			fromArgs(d.Args)
+		}
+		fromFields(b.Children)
+	}
+	for _, f := range v.Fragments {
+		fromFields(f.Children)
+	}
+}
+
+func sortedPosKeys(m map[string]DQLPos) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// --------------------------------------- print ---------------------------------------
+
+// String returns the document as a single line query. Comments are not preserved.
+func (v *DQLDocument) String() string {
+	p := dqlPrinter{}
+	p.document(v)
+	return p.b.String()
+}
+
+// Format returns the query formatted with FormatDQL
+func (v QueryDQL) Format() (QueryDQL, error) {
+	formatted, err := FormatDQL(string(v))
+	return QueryDQL(formatted), err
+}
+
+// FormatDQL normalises whitespace and indentation of a query, including upsert queries used with DoSetb.
+// Each field is on its own line, indented by 2 spaces. Joined queries are merged into a single body,
+// comments are dropped, see DQLDocument.Comments.
+func FormatDQL(q string) (string, error) {
+	doc, err := ParseDQL(q)
+	if err != nil {
+		return "", err
+	}
+	p := dqlPrinter{indent: "  "}
+	p.document(doc)
+	return p.b.String(), nil
+}
+
+// dqlPrinter prints DQL AST. With empty indent, everything is printed on a single line.
+type dqlPrinter struct {
+	b      strings.Builder
+	indent string
+	depth  int
+}
+
+// newline separates elements of a selection set
+func (v *dqlPrinter) newline() {
+	if v.indent == "" {
+		v.b.WriteByte(' ')
+		return
+	}
+	v.b.WriteByte('\n')
+	v.b.WriteString(strings.Repeat(v.indent, v.depth))
+}
+
+func (v *dqlPrinter) open() {
+	v.b.WriteString("{")
+	v.depth++
+}
+
+func (v *dqlPrinter) close() {
+	v.depth--
+	v.newline()
+	v.b.WriteString("}")
+}
+
+func (v *dqlPrinter) document(doc *DQLDocument) {
+	for _, f := range doc.Fragments {
+		v.b.WriteString("fragment " + f.Name + " ")
+		v.selection(f.Children)
+		v.newline()
+	}
+	var schema, blocks []*DQLBlock
+	for _, b := range doc.Blocks {
+		if b.Name == "schema" {
+			schema = append(schema, b)
+		} else {
+			blocks = append(blocks, b)
+		}
+	}
+	for i, b := range schema {
+		if i > 0 {
+			v.newline()
+		}
+		v.block(b)
+	}
+	if len(blocks) == 0 {
+		return
+	}
+	if len(schema) > 0 {
+		v.newline()
+	}
+	if doc.Name != "" || len(doc.Vars) > 0 {
+		v.b.WriteString("query")
+		if doc.Name != "" {
+			v.b.WriteString(" " + doc.Name)
+		}
+		if len(doc.Vars) > 0 {
+			v.b.WriteByte('(')
+			for i, d := range doc.Vars {
+				if i > 0 {
+					v.b.WriteString(", ")
+				}
+				v.b.WriteString(d.Name + ": " + d.Type)
+				if d.Default != "" {
+					v.b.WriteString(" = " + d.Default)
+				}
+			}
+			v.b.WriteByte(')')
+		}
+		v.b.WriteByte(' ')
+	}
+	v.open()
+	for _, b := range blocks {
+		v.newline()
+		v.block(b)
+	}
+	v.close()
+}
+
+func (v *dqlPrinter) block(b *DQLBlock) {
+	if b.Var != "" {
+		v.b.WriteString(b.Var + " as ")
+	}
+	v.b.WriteString(b.Name)
+	v.args(b.Args)
+	v.directives(b.Directives)
+	if len(b.Children) > 0 || b.Name == "schema" {
+		v.b.WriteByte(' ')
+		v.selection(b.Children)
+	}
+}
+
+func (v *dqlPrinter) selection(fields []*DQLField) {
+	if len(fields) == 0 {
+		v.b.WriteString("{}")
+		return
+	}
+	v.open()
+	for _, f := range fields {
+		v.newline()
+		v.field(f)
+	}
+	v.close()
+}
+
+func (v *dqlPrinter) field(f *DQLField) {
+	if f.Alias != "" {
+		v.b.WriteString(f.Alias + ": ")
+	}
+	if f.Var != "" {
+		v.b.WriteString(f.Var + " as ")
+	}
+	if f.Func != nil {
+		v.expr(f.Func)
+	} else {
+		v.b.WriteString(f.Name)
+	}
+	if f.Lang != "" {
+		v.b.WriteString("@" + f.Lang)
+	}
+	v.args(f.Args)
+	v.directives(f.Directives)
+	if len(f.Children) > 0 {
+		v.b.WriteByte(' ')
+		v.selection(f.Children)
+	}
+}
+
+func (v *dqlPrinter) directives(directives []DQLDirective) {
+	for _, d := range directives {
+		v.b.WriteString(" @" + d.Name)
+		v.args(d.Args)
+	}
+}
+
+func (v *dqlPrinter) args(args []DQLArg) {
+	if args == nil {
+		return
+	}
+	v.b.WriteByte('(')
+	for i, a := range args {
+		if i > 0 {
+			v.b.WriteString(", ")
+		}
+		if a.Name != "" {
+			v.b.WriteString(a.Name + ": ")
+		}
+		if a.Var != "" {
+			v.b.WriteString(a.Var + " as ")
+		}
+		v.expr(a.Value)
+	}
+	v.b.WriteByte(')')
+}
+
+func (v *dqlPrinter) expr(e *DQLExpr) {
+	switch e.Kind {
+	case DQLExprValue, DQLExprMath:
+		v.b.WriteString(e.Value)
+	case DQLExprFunc:
+		v.b.WriteString(e.Func + "(")
+		for i, a := range e.Args {
+			if i > 0 {
+				v.b.WriteString(", ")
+			}
+			v.expr(a)
+		}
+		v.b.WriteByte(')')
+	case DQLExprList:
+		v.b.WriteByte('[')
+		for i, a := range e.Args {
+			if i > 0 {
+				v.b.WriteString(", ")
+			}
+			v.expr(a)
+		}
+		v.b.WriteByte(']')
+	case DQLExprNot:
+		v.b.WriteString("NOT ")
+		v.expr(e.Args[0])
+	case DQLExprAnd, DQLExprOr:
+		op := " AND "
+		if e.Kind == DQLExprOr {
+			op = " OR "
+		}
+		if e.Value == "()" {
+			v.b.WriteByte('(')
+		}
+		for i, a := range e.Args {
+			if i > 0 {
+				v.b.WriteString(op)
+			}
+			v.expr(a)
+		}
+		if e.Value == "()" {
+			v.b.WriteByte(')')
+		}
+	}
+}
+
+// --------------------------------------- merge ---------------------------------------
+
+// Merge parses the queries and merges their blocks and `query` headers into a single query document.
+// Unlike Join, it fails on colliding block or variable names. Use MergeDQL to rename them instead.
+func (v QueryDQL) Merge(queries ...QueryDQL) (QueryDQL, error) {
+	merged, _, err := MergeDQL(false, append([]QueryDQL{v}, queries...)...)
+	return merged, err
+}
+
+// MergeDQL merges the queries into a single query document, with one `{ ... }` body and merged `query name($a: ...)` header.
+// Query variables `$a` can be shared between queries, if declared with the same type and default.
+// Colliding block names and `x as` variables are errors, unless rename is set, in which case they get suffixed with index of the query,
+// i.e. `q` of the second query becomes `q_1`. renames[i] maps block names of queries[i] to block names in the merged query, for decoding.
+func MergeDQL(rename bool, queries ...QueryDQL) (merged QueryDQL, renames []map[string]string, err error) {
+	res := &DQLDocument{}
+	blocks := map[string]bool{}
+	vars := map[string]bool{}
+	decls := map[string]DQLVarDecl{}
+	declaredIn := map[string]int{}
+	fragments := map[string]string{}
+	unique := func(taken map[string]bool, name string, i int) string {
+		renamed := fmt.Sprintf("%s_%d", name, i)
+		for n := 2; taken[renamed]; n++ {
+			renamed = fmt.Sprintf("%s_%d_%d", name, i, n)
+		}
+		return renamed
+	}
+
+	for i, q := range queries {
+		doc, err := q.Parse()
+		if err != nil {
+			return "", nil, fmt.Errorf("ndgo: query %d: %w", i, err)
+		}
+		if res.Name == "" {
+			res.Name = doc.Name
+		}
+		for _, d := range doc.Vars {
+			if prev, ok := decls[d.Name]; ok {
+				if prev.Type != d.Type || prev.Default != d.Default {
+					return "", nil, fmt.Errorf("ndgo: query %d: %s declared differently than in query %d", i, d.Name, declaredIn[d.Name])
+				}
+				continue
+			}
+			decls[d.Name], declaredIn[d.Name] = d, i
+			res.Vars = append(res.Vars, d)
+		}
+		for _, f := range doc.Fragments {
+			p := dqlPrinter{}
+			p.selection(f.Children)
+			if prev, ok := fragments[f.Name]; ok {
+				if prev != p.b.String() {
+					return "", nil, fmt.Errorf("ndgo: query %d: fragment %s collides", i, f.Name)
+				}
+				continue
+			}
+			fragments[f.Name] = p.b.String()
+			res.Fragments = append(res.Fragments, f)
+		}
+
+		defs, _ := doc.variables()
+		varRenames := map[string]string{}
+		for _, name := range sortedPosKeys(defs) {
+			if vars[name] {
+				if !rename {
+					return "", nil, fmt.Errorf("ndgo: query %d: variable %s collides", i, name)
+				}
+				varRenames[name] = unique(vars, name, i)
+			}
+			vars[varRenamedOr(varRenames, name)] = true
+		}
+		doc.renameVars(varRenames)
+
+		blockRenames := map[string]string{}
+		for _, b := range doc.Blocks {
+			if b.Name == "var" {
+				continue
+			}
+			name := b.Name
+			if blocks[name] {
+				if !rename {
+					return "", nil, fmt.Errorf("ndgo: query %d: block name %s collides", i, name)
+				}
+				b.Name = unique(blocks, name, i)
+			}
+			blocks[b.Name] = true
+			blockRenames[name] = b.Name
+		}
+		renames = append(renames, blockRenames)
+		res.Blocks = append(res.Blocks, doc.Blocks...)
+	}
+	return QueryDQL(res.String()), renames, nil
+}
+
+func varRenamedOr(renames map[string]string, name string) string {
+	if renamed, ok := renames[name]; ok {
+		return renamed
+	}
+	return name
+}
+
+// renameVars renames `x as` variable definitions and their usages in uid(), val() and math()
+func (v *DQLDocument) renameVars(renames map[string]string) {
+	if len(renames) == 0 {
+		return
+	}
+	var fromExpr func(e *DQLExpr)
+	fromExpr = func(e *DQLExpr) {
+		if e == nil {
+			return
+		}
+		switch {
+		case e.Kind == DQLExprFunc && (e.Func == "uid" || e.Func == "val"):
+			for _, a := range e.Args {
+				if a.Kind == DQLExprValue {
+					a.Value = varRenamedOr(renames, a.Value)
+				}
+			}
+		case e.Kind == DQLExprMath:
+			e.Value = renameMathVars(e.Value, renames)
+		}
+		for _, a := range e.Args {
+			fromExpr(a)
+		}
+	}
+	v.walkExprs(fromExpr)
+
+	var fromFields func(fields []*DQLField)
+	fromFields = func(fields []*DQLField) {
+		for _, f := range fields {
+			f.Var = varRenamedOr(renames, f.Var)
+			for _, d := range f.Directives {
+				for i := range d.Args {
+					d.Args[i].Var = varRenamedOr(renames, d.Args[i].Var)
+				}
+			}
+			fromFields(f.Children)
+		}
+	}
+	for _, b := range v.Blocks {
+		b.Var = varRenamedOr(renames, b.Var)
+		fromFields(b.Children)
+	}
+	for _, f := range v.Fragments {
+		fromFields(f.Children)
+	}
+}
+
+// renameMathVars renames variables in raw math expression
+func renameMathVars(math string, renames map[string]string) string {
+	var b strings.Builder
+	for i := 0; i < len(math); {
+		if !isDQLMathIdentChar(math[i]) {
+			b.WriteByte(math[i])
+			i++
+			continue
+		}
+		j := i
+		for j < len(math) && isDQLMathIdentChar(math[j]) {
+			j++
+		}
+		b.WriteString(varRenamedOr(renames, math[i:j]))
+		i = j
+	}
+	return b.String()
+}
+
+func isDQLMathIdentChar(c byte) bool {
+	return isDQLIdentChar(c) && c != '+' && c != '-'
+}
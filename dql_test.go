@@ -0,0 +1,284 @@
+package ndgo_test
+
+import (
+	"testing"
+
+	"github.com/ppp225/ndgo/v5"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDQL(t *testing.T) {
+	q := ndgo.QueryDQL(`
+	query people($name: string = "Keanu", $first: int) {
+	  # people with friends
+	  f as var(func: eq(name@en, $name)) @filter(has(friend) AND NOT (eq(age, 1) OR uid_in(friend, 0x1))) {
+	    c as count(friend)
+	  }
+	  q(func: uid(f), first: $first, orderasc: val(c)) @cascade {
+	    uid
+	    alias: name@en:de:.
+	    friends: friend (first: 10, orderdesc: age) @facets(since) {
+	      ~knows { uid }
+	    }
+	    total: val(c)
+	    score: math(c * 2)
+	    expand(_all_)
+	  }
+	}`)
+	doc, err := q.Parse()
+	require.NoError(t, err)
+	require.Equal(t, "people", doc.Name)
+	require.Equal(t, []ndgo.DQLVarDecl{
+		{Name: "$name", Type: "string", Default: `"Keanu"`, Pos: ndgo.DQLPos{Line: 2, Column: 15}},
+		{Name: "$first", Type: "int", Pos: ndgo.DQLPos{Line: 2, Column: 40}},
+	}, doc.Vars)
+	require.Len(t, doc.Blocks, 2)
+	require.Equal(t, []string{"q"}, doc.BlockNames())
+
+	filter := doc.Blocks[0].Directives[0]
+	require.Equal(t, "filter", filter.Name)
+	require.Equal(t, ndgo.DQLExprAnd, filter.Args[0].Value.Kind)
+	require.Equal(t, ndgo.DQLExprNot, filter.Args[0].Value.Args[1].Kind)
+
+	fields := doc.Blocks[1].Children
+	require.Equal(t, "name", fields[1].Name)
+	require.Equal(t, "alias", fields[1].Alias)
+	require.Equal(t, "en:de:.", fields[1].Lang)
+	require.Equal(t, "friends", fields[2].Alias)
+	require.Equal(t, "orderdesc", fields[2].Args[1].Name)
+	require.Equal(t, "~knows", fields[2].Children[0].Name)
+	require.Equal(t, "c * 2", fields[4].Func.Args[0].Value)
+	require.Equal(t, "expand", fields[5].Func.Func)
+
+	defined, used := doc.Variables()
+	require.Equal(t, []string{"c", "f"}, defined)
+	require.Equal(t, []string{"c", "f"}, used)
+	require.Equal(t, []string{"age", "friend", "knows", "name"}, doc.Predicates())
+	require.Empty(t, doc.Lint(map[string]string{"$first": "1"}))
+}
+
+func TestParseDQLJoin(t *testing.T) {
+	q := ndgo.Query{}.GetPredExpandType("a", "eq", "name", "Keanu", "", "", "uid", "_all_").
+		Join(ndgo.Query{}.GetUIDExpandType("b", "uid", "0x1", "", "", "", "_all_"))
+	doc, err := q.Parse()
+	require.NoError(t, err)
+	require.Equal(t, []string{"a", "b"}, doc.BlockNames())
+
+	_, err = ndgo.ParseDQL("[" + string(q) + "]")
+	require.NoError(t, err)
+}
+
+func TestParseDQLForms(t *testing.T) {
+	var testData = []string{
+		"{\n\tq1(func: eq(name, \"a\")) {\n\t\tuid: uid\n\t},\n\tq2(func: eq(name, \"b\")) { uid: uid }\n}",
+		`{ f(func: has(name)) }`,
+		`{ l as var(func: eq(name, "k")) }`,
+		`schema {}`,
+		`schema(pred: [name, age]) { type index }`,
+		`{ q(func: uid(0x1)) { expand(_all_) { expand(_all_) } } }`,
+		`{ path as shortest(from: 0x1, to: 0x2, numpaths: 2) { friend @facets(weight) } p(func: uid(path)) { name } }`,
+		`{ q(func: near(loc, [-122.4, 37.7], 1000)) @recurse(depth: 3, loop: false) { name friend } }`,
+		`{ q(func: type(Person)) @groupby(age) { count(uid) } }`,
+		`{ q(func: anyofterms(name, "a b")) @filter(ge(count(friend), 2)) { name @facets(eq(close, true)) } }`,
+		`fragment f { name } { q(func: has(name)) { ...f } }`,
+		`{ q(func: regexp(name, /^Steven.*$/i)) @filter(regexp(city, /^B/)) { uid } }`,
+		`{ q(func: uid(0x1)) { friend @facets(w as weight, since) { uid } total: sum(val(w)) } }`,
+		string(ndgo.Query{}.GetPredExpandType("q", "eq", "name", `["a" "b"]`, ",first:1", "@cascade", "uid dgraph.type", "Person")),
+	}
+	for i, tt := range testData {
+		_, err := ndgo.ParseDQL(tt)
+		require.NoError(t, err, "Test i=%d", i)
+	}
+}
+
+func TestParseDQLErrors(t *testing.T) {
+	var testData = []struct {
+		in  string
+		pos ndgo.DQLPos
+	}{
+		{in: ``, pos: ndgo.DQLPos{Line: 1, Column: 1}},
+		{in: `{ q(func: has(name) { uid } }`, pos: ndgo.DQLPos{Line: 1, Column: 21}},
+		{in: "{\n  q(func: has(name)) { uid }", pos: ndgo.DQLPos{Line: 2, Column: 29}},
+		{in: `query q(name: string) { q(func: eq(name, $name)) { uid } }`, pos: ndgo.DQLPos{Line: 1, Column: 9}},
+		{in: `{ q(func: eq(name, "x)) { uid } }`, pos: ndgo.DQLPos{Line: 1, Column: 20}},
+		{in: `{ q(func: has(name)) { uid } } }`, pos: ndgo.DQLPos{Line: 1, Column: 32}},
+		{in: `{ q(func: regexp(name, /^Steven)) { uid } }`, pos: ndgo.DQLPos{Line: 1, Column: 24}},
+	}
+	for i, tt := range testData {
+		_, err := ndgo.ParseDQL(tt.in)
+		require.Error(t, err, "Test i=%d", i)
+		derr, ok := err.(*ndgo.DQLError)
+		require.True(t, ok, "Test i=%d", i)
+		require.Equal(t, tt.pos, derr.DQLPos, "Test i=%d: %v", i, err)
+	}
+}
+
+func TestLintDQL(t *testing.T) {
+	var testData = []struct {
+		in   ndgo.QueryDQL
+		vars map[string]string
+		out  []string
+	}{
+		{
+			in:  ndgo.QueryDQL(`{ q(func: has(name)) { uid } }`).Join(`{ q(func: has(age)) { uid } }`),
+			out: []string{`1:34: duplicate block name "q", first defined at 1:3`},
+		},
+		{
+			in: `{ a as var(func: has(name)) { b as age } q(func: uid(a, c)) { uid } }`,
+			out: []string{
+				`1:57: variable "c" is used, but not defined`,
+				`1:31: variable "b" is defined, but not used`,
+			},
+		},
+		{
+			in:   `query q($name: string) { q(func: eq(name, $name), first: $first) { uid } }`,
+			vars: map[string]string{"name": "Keanu"},
+			out: []string{
+				`1:58: query variable $first is not declared in query header`,
+				`0:0: vars key "name" must start with '$'`,
+				`1:9: query variable $name has no value and no default`,
+			},
+		},
+		{
+			in:  `{ q(func: has(name) { uid } }`,
+			out: []string{`1:21: expected ',' or ')', got "{"`},
+		},
+		{
+			in:  `{ q(func: regexp(name, /^a/i)) { friend @facets(w as weight, u as since) { uid } t: sum(val(w)) } }`,
+			out: []string{`1:41: variable "u" is defined, but not used`},
+		},
+	}
+	for i, tt := range testData {
+		var issues []string
+		for _, issue := range ndgo.LintDQL(string(tt.in), tt.vars) {
+			issues = append(issues, issue.String())
+		}
+		require.Equal(t, tt.out, issues, "Test i=%d", i)
+	}
+}
+
+func TestDQLDocumentString(t *testing.T) {
+	var testData = []struct {
+		in  string
+		out string
+	}{
+		{
+			in: `
+			query q($a: string = "x") {
+			  # comment
+			  v as var(func: eq(name@en, $a)) @filter(has(age) AND (NOT has(friend) OR eq(age, [1 2])))
+			  q(func: uid(v), first: 1) @cascade {
+			    uid
+			    n: name@en:de
+			    friend @facets(since) { c as count(friend) }
+			    s: math(c*2)
+			  }
+			}`,
+			out: `query q($a: string = "x") { v as var(func: eq(name@en, $a)) @filter(has(age) AND (NOT has(friend) OR eq(age, [1, 2]))) ` +
+				`q(func: uid(v), first: 1) @cascade { uid n: name@en:de friend @facets(since) { c as count(friend) } s: math(c*2) } }`,
+		},
+		{in: `schema(pred: [name]) {type}`, out: `schema(pred: [name]) { type }`},
+		{in: `fragment f {name} {q(func: has(name)) {...f}}`, out: `fragment f { name } { q(func: has(name)) { ...f } }`},
+		{in: `{var(func: has(age)) {a as age} q() {m: max(val(a))}}`, out: `{ var(func: has(age)) { a as age } q() { m: max(val(a)) } }`},
+		{in: `{q(func: regexp(name, /^Steven.*\/x$/i)) {uid}}`, out: `{ q(func: regexp(name, /^Steven.*\/x$/i)) { uid } }`},
+		{in: `{q(func: uid(0x1)) {friend @facets(w as weight) {uid} t: sum(val(w))}}`, out: `{ q(func: uid(0x1)) { friend @facets(w as weight) { uid } t: sum(val(w)) } }`},
+	}
+	for i, tt := range testData {
+		doc, err := ndgo.ParseDQL(tt.in)
+		require.NoError(t, err, "Test i=%d", i)
+		require.Equal(t, tt.out, doc.String(), "Test i=%d", i)
+		// printed query parses to the same document
+		again, err := ndgo.ParseDQL(doc.String())
+		require.NoError(t, err, "Test i=%d", i)
+		require.Equal(t, tt.out, again.String(), "Test i=%d", i)
+	}
+}
+
+func TestMergeDQL(t *testing.T) {
+	q1 := ndgo.QueryDQL(`query a($name: string) { v as var(func: eq(name, $name)) q(func: uid(v)) { uid } }`)
+	q2 := ndgo.QueryDQL(`query b($name: string, $age: int) { v as var(func: eq(age, $age)) q(func: uid(v)) { c as count(friend) s: math(c + 1) } }`)
+	q3 := ndgo.QueryDQL(`{ other(func: has(name)) { uid } }`)
+
+	_, err := q1.Merge(q2)
+	require.EqualError(t, err, "ndgo: query 1: variable v collides")
+	merged, err := q1.Merge(q3)
+	require.NoError(t, err)
+	require.Equal(t, ndgo.QueryDQL(`query a($name: string) { v as var(func: eq(name, $name)) q(func: uid(v)) { uid } other(func: has(name)) { uid } }`), merged)
+
+	merged, renames, err := ndgo.MergeDQL(true, q1, q2, q3)
+	require.NoError(t, err)
+	require.Equal(t, ndgo.QueryDQL(`query a($name: string, $age: int) { v as var(func: eq(name, $name)) q(func: uid(v)) { uid } `+
+		`v_1 as var(func: eq(age, $age)) q_1(func: uid(v_1)) { c as count(friend) s: math(c + 1) } other(func: has(name)) { uid } }`), merged)
+	require.Equal(t, []map[string]string{{"q": "q"}, {"q": "q_1"}, {"other": "other"}}, renames)
+	require.Empty(t, merged.Lint())
+
+	_, _, err = ndgo.MergeDQL(true, q1, `query c($name: int) { x(func: eq(age, $name)) { uid } }`)
+	require.EqualError(t, err, "ndgo: query 1: $name declared differently than in query 0")
+	_, _, err = ndgo.MergeDQL(true, q1, `{ x(func: has(name) { uid } }`)
+	require.EqualError(t, err, `ndgo: query 1: ndgo: dql 1:21: expected ',' or ')', got "{"`)
+}
+
+func TestFormatDQL(t *testing.T) {
+	var testData = []struct {
+		in  ndgo.QueryDQL
+		out string
+	}{
+		{
+			in: ndgo.Query{}.GetPredExpandType("q", "eq", "name", "Keanu", ",first:1", "@cascade", "uid dgraph.type", "_all_"),
+			out: `{
+  q(func: eq(name, "Keanu"), first: 1) @cascade {
+    uid
+    dgraph.type
+    expand(_all_)
+  }
+}`,
+		},
+		{
+			in: `query q($a:string){v as var(func:eq(name,$a)) # comment
+			q(func:uid(v)){n:name@en friend@facets(since){uid}}}`,
+			out: `query q($a: string) {
+  v as var(func: eq(name, $a))
+  q(func: uid(v)) {
+    n: name@en
+    friend @facets(since) {
+      uid
+    }
+  }
+}`,
+		},
+		{
+			in: `fragment f{name age} {q(func:has(name)){...f}}`,
+			out: `fragment f {
+  name
+  age
+}
+{
+  q(func: has(name)) {
+    ...f
+  }
+}`,
+		},
+		{in: `schema{}`, out: `schema {}`},
+	}
+	for i, tt := range testData {
+		out, err := tt.in.Format()
+		require.NoError(t, err, "Test i=%d", i)
+		require.Equal(t, tt.out, string(out), "Test i=%d", i)
+		// formatting is idempotent
+		again, err := out.Format()
+		require.NoError(t, err, "Test i=%d", i)
+		require.Equal(t, out, again, "Test i=%d", i)
+	}
+	_, err := ndgo.FormatDQL(`{ q(func: has(name) { uid } }`)
+	require.Error(t, err)
+}
+
+func TestFormatDQLAggregate(t *testing.T) {
+	q, err := ndgo.QueryDQL(ndgo.Query{}.Max("has(age)", "", "age")).Format()
+	require.NoError(t, err)
+	require.Equal(t, ndgo.QueryDQL(ndgo.Query{}.Max("has(age)", "", "age")), q)
+
+	doc, err := ndgo.ParseDQL("# a\n{ q(func: has(name)) { uid } } # b")
+	require.NoError(t, err)
+	require.Equal(t, 2, doc.Comments)
+}
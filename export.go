@@ -0,0 +1,381 @@
+package ndgo
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// --------------------------------------- options ---------------------------------------
+
+// ExportFormat is the output format of Export
+type ExportFormat string
+
+// Available export formats
+const (
+	ExportRDF  ExportFormat = "rdf"
+	ExportJSON ExportFormat = "json"
+)
+
+// ExportOptions configures Export. Zero values are replaced with defaults.
+type ExportOptions struct {
+	// Types to export. If empty, all predicates are exported, by walking nodes which have them.
+	// Nodes found by several walks are exported once, with predicates of all Types.
+	Types    []string
+	Format   ExportFormat // default ExportRDF
+	PageSize int          // nodes per query, default 1000
+	Gzip     bool
+	// BlankNodes writes uids as blank nodes, i.e. `_:0x1`, so export can be loaded into another cluster
+	BlankNodes bool
+}
+
+// ExportStats reports what was exported
+type ExportStats struct {
+	Nodes  int64 // exported node objects
+	NQuads int64 // written N-Quads, only for ExportRDF
+}
+
+// --------------------------------------- export ---------------------------------------
+
+// Export walks nodes of given types (or all predicates) with paginated queries and writes them as N-Quads or JSON.
+// Facets and language tags are preserved. Use a read-only txn: `ndgo.NewTxn(ctx, dg.NewReadOnlyTxn())`.
+func Export(txn *Txn, w io.Writer, opts ExportOptions) (stats ExportStats, err error) {
+	if opts.Format == "" {
+		opts.Format = ExportRDF
+	}
+	if opts.Format != ExportRDF && opts.Format != ExportJSON {
+		return stats, fmt.Errorf("ndgo: unknown export format %q", opts.Format)
+	}
+	if opts.PageSize <= 0 {
+		opts.PageSize = 1000
+	}
+	schema, err := txn.Schema()
+	if err != nil {
+		return stats, err
+	}
+	roots, preds, err := exportWalks(schema, opts.Types)
+	if err != nil {
+		return stats, err
+	}
+
+	if opts.Gzip {
+		gz := gzip.NewWriter(w)
+		defer func() {
+			if cerr := gz.Close(); err == nil {
+				err = cerr
+			}
+		}()
+		w = gz
+	}
+	bw := bufio.NewWriter(w)
+	defer func() {
+		if ferr := bw.Flush(); err == nil {
+			err = ferr
+		}
+	}()
+
+	e := exporter{w: bw, schema: schema, opts: opts, stats: &stats, preds: preds}
+	if len(roots) > 1 {
+		e.seen = map[string]bool{}
+	}
+	if opts.Format == ExportJSON {
+		bw.WriteByte('[')
+	}
+	for _, root := range roots {
+		if err = e.walk(txn, root); err != nil {
+			return stats, err
+		}
+	}
+	if opts.Format == ExportJSON {
+		bw.WriteString("\n]\n")
+	}
+	return stats, nil
+}
+
+// exportWalks returns root functions of paginated queries, and predicates they select. Every walk selects all predicates,
+// so a node found by several walks is complete when first written, and skipped later.
+func exportWalks(schema *Schema, types []string) (roots, preds []string, err error) {
+	exportable := func(pred string) bool {
+		p := schema.Predicate(pred)
+		return p != nil && p.Type != "password" && (pred == "dgraph.type" || !strings.HasPrefix(pred, "dgraph."))
+	}
+	if len(types) == 0 {
+		for _, p := range schema.Predicates {
+			if exportable(p.Predicate) {
+				roots = append(roots, "has("+p.Predicate+")")
+				preds = append(preds, p.Predicate)
+			}
+		}
+		return roots, preds, nil
+	}
+	selected := map[string]bool{"dgraph.type": true}
+	preds = []string{"dgraph.type"}
+	for _, typ := range types {
+		t := schema.Type(typ)
+		if t == nil {
+			return nil, nil, fmt.Errorf("ndgo: type %s not found in schema", typ)
+		}
+		roots = append(roots, "type("+typ+")")
+		for _, f := range t.Fields {
+			if exportable(f.Name) && !selected[f.Name] {
+				selected[f.Name] = true
+				preds = append(preds, f.Name)
+			}
+		}
+	}
+	return roots, preds, nil
+}
+
+type exporter struct {
+	w      *bufio.Writer
+	schema *Schema
+	opts   ExportOptions
+	stats  *ExportStats
+	preds  []string
+	// seen are uids of written nodes, when there are several walks
+	seen map[string]bool
+}
+
+func (v *exporter) walk(txn *Txn, root string) error {
+	var sel strings.Builder
+	for _, pred := range v.preds {
+		p := v.schema.Predicate(pred)
+		switch {
+		case p.Type == "uid":
+			fmt.Fprintf(&sel, "\n    %s @facets { uid }", pred)
+		case p.Lang:
+			fmt.Fprintf(&sel, "\n    %s@* @facets", pred)
+		default:
+			fmt.Fprintf(&sel, "\n    %s @facets", pred)
+		}
+	}
+	after := ""
+	for {
+		resp, err := txn.Query(fmt.Sprintf(`
+{
+  q(func: %s, first: %d%s) {
+    uid%s
+  }
+}`, root, v.opts.PageSize, after, sel.String()))
+		if err != nil {
+			return err
+		}
+		var decode struct {
+			Q []map[string]interface{} `json:"q"`
+		}
+		dec := json.NewDecoder(bytes.NewReader(resp.GetJson()))
+		dec.UseNumber()
+		if err := dec.Decode(&decode); err != nil {
+			return err
+		}
+		for _, node := range decode.Q {
+			if v.seen != nil {
+				uid, _ := node["uid"].(string)
+				if v.seen[uid] {
+					continue
+				}
+				v.seen[uid] = true
+			}
+			if err := v.write(node); err != nil {
+				return err
+			}
+		}
+		if len(decode.Q) < v.opts.PageSize {
+			return nil
+		}
+		after = fmt.Sprintf(", after: %s", decode.Q[len(decode.Q)-1]["uid"])
+	}
+}
+
+func (v *exporter) write(node map[string]interface{}) error {
+	if v.opts.Format == ExportJSON {
+		if v.opts.BlankNodes {
+			blankUIDs(node)
+		}
+		b, err := json.Marshal(node)
+		if err != nil {
+			return err
+		}
+		if v.stats.Nodes > 0 {
+			v.w.WriteByte(',')
+		}
+		v.w.WriteString("\n  ")
+		v.w.Write(b)
+		v.stats.Nodes++
+		return nil
+	}
+
+	uid, _ := node["uid"].(string)
+	nquads, err := nodeToNQuads(uid, node, v.schema)
+	if err != nil {
+		return err
+	}
+	for _, nq := range nquads {
+		if v.opts.BlankNodes {
+			nq.Subject = "_:" + nq.Subject
+			if !nq.IsLiteral() {
+				nq.ObjectID = "_:" + nq.ObjectID
+			}
+		}
+		v.w.WriteString(nq.String())
+		v.w.WriteByte('\n')
+	}
+	v.stats.Nodes++
+	v.stats.NQuads += int64(len(nquads))
+	return nil
+}
+
+// blankUIDs rewrites all uid values to blank nodes, in place
+func blankUIDs(val interface{}) {
+	switch v := val.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			if s, ok := child.(string); ok && key == "uid" {
+				v[key] = "_:" + s
+				continue
+			}
+			blankUIDs(child)
+		}
+	case []interface{}:
+		for _, child := range v {
+			blankUIDs(child)
+		}
+	}
+}
+
+// --------------------------------------- query response to n-quads ---------------------------------------
+
+// nodeToNQuads converts a single node of a query response (decoded with UseNumber) to N-Quads.
+// Keys may contain language `pred@en` and facets `pred|facet`. Nested nodes are only referenced by uid.
+func nodeToNQuads(uid string, node map[string]interface{}, schema *Schema) (nquads []NQuad, err error) {
+	keys := make([]string, 0, len(node))
+	for key := range node {
+		if key != "uid" && !strings.Contains(key, "|") {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		pred, lang := key, ""
+		if idx := strings.IndexByte(key, '@'); idx >= 0 {
+			pred, lang = key[:idx], key[idx+1:]
+		}
+		datatype := ""
+		if p := schema.Predicate(pred); p != nil {
+			datatype = datatypeOf(p.Type)
+		}
+		facets := facetsOf(node, key)
+
+		values, isList := node[key].([]interface{})
+		if !isList {
+			values = []interface{}{node[key]}
+		}
+		for i, val := range values {
+			nq := NQuad{Subject: uid, Predicate: pred}
+			if child, ok := val.(map[string]interface{}); ok && !isGeoJSON(child) {
+				nq.ObjectID, _ = child["uid"].(string)
+				if nq.ObjectID == "" {
+					return nil, fmt.Errorf("ndgo: node %s predicate %s: nested object without uid", uid, key)
+				}
+				nq.Facets = facetsOf(child, key).at(0, false)
+			} else {
+				if nq.ObjectValue, err = literalOf(val); err != nil {
+					return nil, err
+				}
+				nq.Lang = lang
+				if lang == "" {
+					nq.Datatype = datatype
+				}
+				nq.Facets = facets.at(i, isList)
+			}
+			nquads = append(nquads, nq)
+		}
+	}
+	return nquads, nil
+}
+
+// listFacets holds facets of a key, which for scalar lists are maps indexed by position, i.e. `{"0": "a"}`
+type listFacets map[string]interface{}
+
+func facetsOf(node map[string]interface{}, key string) listFacets {
+	var res listFacets
+	prefix := key + "|"
+	for k, val := range node {
+		if strings.HasPrefix(k, prefix) {
+			if res == nil {
+				res = listFacets{}
+			}
+			res[k[len(prefix):]] = val
+		}
+	}
+	return res
+}
+
+func (v listFacets) at(i int, isList bool) (facets []Facet) {
+	keys := make([]string, 0, len(v))
+	for key := range v {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		val := v[key]
+		if indexed, ok := val.(map[string]interface{}); ok && isList {
+			if val, ok = indexed[strconv.Itoa(i)]; !ok {
+				continue
+			}
+		}
+		facets = append(facets, Facet{Key: key, Value: facetValueOf(val)})
+	}
+	return facets
+}
+
+func facetValueOf(val interface{}) interface{} {
+	switch f := val.(type) {
+	case json.Number:
+		if i, err := f.Int64(); err == nil {
+			return i
+		}
+		fl, _ := f.Float64()
+		return fl
+	case float64:
+		return f
+	case bool:
+		return f
+	case string:
+		if t, err := time.Parse(time.RFC3339Nano, f); err == nil {
+			return t
+		}
+		return f
+	default:
+		return fmt.Sprint(f)
+	}
+}
+
+func literalOf(val interface{}) (string, error) {
+	switch l := val.(type) {
+	case string:
+		return l, nil
+	case json.Number:
+		return l.String(), nil
+	case bool:
+		return strconv.FormatBool(l), nil
+	case nil:
+		return "", nil
+	default:
+		b, err := json.Marshal(l)
+		return string(b), err
+	}
+}
+
+func isGeoJSON(obj map[string]interface{}) bool {
+	_, hasType := obj["type"]
+	_, hasCoords := obj["coordinates"]
+	return hasType && hasCoords
+}
@@ -0,0 +1,70 @@
+package ndgo_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"testing"
+
+	"github.com/dgraph-io/dgo/v210/protos/api"
+	"github.com/ppp225/ndgo/v5"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExport(t *testing.T) {
+	dg := dgNewClient()
+	defer setupTeardown(dg)()
+
+	require.NoError(t, dg.Alter(context.Background(), &api.Operation{Schema: `type TestOther { testName }`}))
+	defer dg.Alter(context.Background(), &api.Operation{DropOp: api.Operation_TYPE, DropValue: "TestOther"})
+	txn := ndgo.NewTxnWithoutContext(dg.NewTxn())
+	defer txn.Discard()
+	_, err := ndgo.SetRDF(`
+		_:a <testName> "first" .
+		_:a <testAttribute> "attr \"quoted\"" (since=2006, note="x") .
+		_:a <dgraph.type> "TestType" .
+		_:a <dgraph.type> "TestOther" .
+		_:b <testName> "second" .
+		_:b <dgraph.type> "TestType" .
+		_:a <testEdge> _:b (weight=0.5) .
+	`).Run(txn)
+	require.NoError(t, err)
+	require.NoError(t, txn.Commit())
+
+	// rdf, by type
+	txn = ndgo.NewTxnWithoutContext(dg.NewReadOnlyTxn())
+	defer txn.Discard()
+	var out bytes.Buffer
+	stats, err := ndgo.Export(txn, &out, ndgo.ExportOptions{Types: []string{testType}, PageSize: 1})
+	require.NoError(t, err)
+	t.Log(out.String())
+	require.EqualValues(t, 2, stats.Nodes)
+	require.EqualValues(t, 7, stats.NQuads)
+	require.Contains(t, out.String(), `<testAttribute> "attr \"quoted\"" (note="x", since=2006) .`)
+	require.Contains(t, out.String(), `(weight=0.5) .`)
+
+	// nodes with several types are exported once
+	out.Reset()
+	stats, err = ndgo.Export(txn, &out, ndgo.ExportOptions{Types: []string{testType, "TestOther"}, PageSize: 1})
+	require.NoError(t, err)
+	require.EqualValues(t, 2, stats.Nodes)
+	require.EqualValues(t, 7, stats.NQuads)
+
+	// json, all predicates, gzipped, blank nodes
+	out.Reset()
+	stats, err = ndgo.Export(txn, &out, ndgo.ExportOptions{Format: ndgo.ExportJSON, Gzip: true, BlankNodes: true})
+	require.NoError(t, err)
+	gz, err := gzip.NewReader(&out)
+	require.NoError(t, err)
+	data, err := ioutil.ReadAll(gz)
+	require.NoError(t, err)
+	t.Log(string(data))
+	var decode []map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &decode))
+	require.Len(t, decode, int(stats.Nodes))
+	require.EqualValues(t, 2, stats.Nodes, "nodes should be exported once, with all predicates")
+	require.Contains(t, string(data), `"uid":"_:0x`)
+	require.Contains(t, string(data), `"testAttribute|since":2006`)
+}
@@ -0,0 +1,207 @@
+package ndgo
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// --------------------------------------- geo types ---------------------------------------
+
+// Geometry is a GeoJSON value of a geo predicate: Point, Polygon or MultiPolygon
+type Geometry interface {
+	json.Marshaler
+	// coordinates returns GeoJSON coordinates, as used by geo functions
+	coordinates() (string, error)
+}
+
+// Point is a GeoJSON point. Fields are named, as GeoJSON orders coordinates [longitude, latitude].
+type Point struct {
+	Lng float64
+	Lat float64
+}
+
+// Polygon is a GeoJSON polygon of linear rings, outer first, then holes. Rings are closed when marshalled,
+// so the last point may be omitted.
+type Polygon [][]Point
+
+// MultiPolygon is a GeoJSON multi polygon
+type MultiPolygon []Polygon
+
+type geoJSON struct {
+	Type        string          `json:"type"`
+	Coordinates json.RawMessage `json:"coordinates"`
+}
+
+// MarshalJSON encodes GeoJSON object
+func (v Point) MarshalJSON() ([]byte, error) { return marshalGeo("Point", v) }
+
+// MarshalJSON encodes GeoJSON object
+func (v Polygon) MarshalJSON() ([]byte, error) { return marshalGeo("Polygon", v) }
+
+// MarshalJSON encodes GeoJSON object
+func (v MultiPolygon) MarshalJSON() ([]byte, error) { return marshalGeo("MultiPolygon", v) }
+
+// UnmarshalJSON decodes GeoJSON object, or string containing it
+func (v *Point) UnmarshalJSON(data []byte) error {
+	var coords [2]float64
+	if err := unmarshalGeo(data, "Point", &coords); err != nil {
+		return err
+	}
+	*v = Point{Lng: coords[0], Lat: coords[1]}
+	return nil
+}
+
+// UnmarshalJSON decodes GeoJSON object, or string containing it
+func (v *Polygon) UnmarshalJSON(data []byte) error {
+	var coords [][][2]float64
+	if err := unmarshalGeo(data, "Polygon", &coords); err != nil {
+		return err
+	}
+	*v = polygonOf(coords)
+	return nil
+}
+
+// UnmarshalJSON decodes GeoJSON object, or string containing it
+func (v *MultiPolygon) UnmarshalJSON(data []byte) error {
+	var coords [][][][2]float64
+	if err := unmarshalGeo(data, "MultiPolygon", &coords); err != nil {
+		return err
+	}
+	res := make(MultiPolygon, len(coords))
+	for i, polygon := range coords {
+		res[i] = polygonOf(polygon)
+	}
+	*v = res
+	return nil
+}
+
+func (v Point) coordinates() (string, error) {
+	if v.Lat < -90 || v.Lat > 90 || v.Lng < -180 || v.Lng > 180 {
+		return "", fmt.Errorf("ndgo: point out of range, lng %g, lat %g", v.Lng, v.Lat)
+	}
+	return "[" + formatCoord(v.Lng) + ", " + formatCoord(v.Lat) + "]", nil
+}
+
+func (v Polygon) coordinates() (string, error) {
+	rings := make([]string, len(v))
+	for i, ring := range v {
+		if len(ring) > 0 && ring[0] != ring[len(ring)-1] {
+			ring = append(ring[:len(ring):len(ring)], ring[0])
+		}
+		if len(ring) < 4 {
+			return "", fmt.Errorf("ndgo: polygon ring %d needs at least 3 points", i)
+		}
+		points := make([]string, len(ring))
+		for j, p := range ring {
+			var err error
+			if points[j], err = p.coordinates(); err != nil {
+				return "", err
+			}
+		}
+		rings[i] = "[" + strings.Join(points, ", ") + "]"
+	}
+	return "[" + strings.Join(rings, ", ") + "]", nil
+}
+
+func (v MultiPolygon) coordinates() (string, error) {
+	polygons := make([]string, len(v))
+	for i, p := range v {
+		var err error
+		if polygons[i], err = p.coordinates(); err != nil {
+			return "", err
+		}
+	}
+	return "[" + strings.Join(polygons, ", ") + "]", nil
+}
+
+func marshalGeo(typ string, g Geometry) ([]byte, error) {
+	coords, err := g.coordinates()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(geoJSON{Type: typ, Coordinates: json.RawMessage(coords)})
+}
+
+func unmarshalGeo(data []byte, typ string, coords interface{}) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		data = []byte(s)
+	}
+	var g geoJSON
+	if err := json.Unmarshal(data, &g); err != nil {
+		return fmt.Errorf("ndgo: invalid geojson: %w", err)
+	}
+	if g.Type != typ {
+		return fmt.Errorf("ndgo: expected geojson %s, got %q", typ, g.Type)
+	}
+	if err := json.Unmarshal(g.Coordinates, coords); err != nil {
+		return fmt.Errorf("ndgo: invalid geojson %s coordinates: %w", typ, err)
+	}
+	return nil
+}
+
+func polygonOf(coords [][][2]float64) Polygon {
+	res := make(Polygon, len(coords))
+	for i, ring := range coords {
+		res[i] = make([]Point, len(ring))
+		for j, c := range ring {
+			res[i][j] = Point{Lng: c[0], Lat: c[1]}
+		}
+	}
+	return res
+}
+
+func formatCoord(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// GeoString returns GeoJSON of g, as used in N-Quads with geo:geojson datatype
+func GeoString(g Geometry) (string, error) {
+	b, err := g.MarshalJSON()
+	return string(b), err
+}
+
+// --------------------------------------- query helpers ---------------------------------------
+
+// SetGeo Usage: ndgo.Query{}.SetGeo(uid, predicate, ndgo.Point{Lng: 15.9, Lat: 52.1})
+func (Query) SetGeo(uid, predicate string, g Geometry) (SetRDF, error) {
+	geo, err := GeoString(g)
+	if err != nil {
+		return "", err
+	}
+	return SetRDF(NQuad{Subject: uid, Predicate: predicate, ObjectValue: geo, Datatype: GeoJSON}.String() + "\n"), nil
+}
+
+// Near returns geo function matching pred within distance in meters from p, i.e. `near(loc, [15.9, 52.1], 1000)`
+func (Query) Near(pred string, p Point, meters float64) (string, error) {
+	coords, err := p.coordinates()
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("near(%s, %s, %s)", pred, coords, formatCoord(meters)), nil
+}
+
+// Within returns geo function matching pred lying within polygon g
+func (Query) Within(pred string, g Geometry) (string, error) {
+	return geoFunc("within", pred, g)
+}
+
+// Contains returns geo function matching pred, which contains point or polygon g
+func (Query) Contains(pred string, g Geometry) (string, error) {
+	return geoFunc("contains", pred, g)
+}
+
+// Intersects returns geo function matching pred, which intersects polygon g
+func (Query) Intersects(pred string, g Geometry) (string, error) {
+	return geoFunc("intersects", pred, g)
+}
+
+func geoFunc(fx, pred string, g Geometry) (string, error) {
+	coords, err := g.coordinates()
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s(%s, %s)", fx, pred, coords), nil
+}
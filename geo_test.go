@@ -0,0 +1,73 @@
+package ndgo_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/ppp225/ndgo/v5"
+	"github.com/stretchr/testify/require"
+)
+
+type geoPlace struct {
+	Loc  *ndgo.Point       `json:"loc,omitempty"`
+	Area ndgo.Polygon      `json:"area,omitempty"`
+	Many ndgo.MultiPolygon `json:"many,omitempty"`
+}
+
+func TestGeoMarshal(t *testing.T) {
+	square := ndgo.Polygon{{{Lng: 0, Lat: 0}, {Lng: 1, Lat: 0}, {Lng: 1, Lat: 1}, {Lng: 0, Lat: 1}}}
+	place := geoPlace{
+		Loc:  &ndgo.Point{Lng: 15.9, Lat: 52.1},
+		Area: square,
+		Many: ndgo.MultiPolygon{square},
+	}
+	b, err := ndgo.Marshal(place)
+	require.NoError(t, err)
+	ring := `[[0, 0], [1, 0], [1, 1], [0, 1], [0, 0]]`
+	require.JSONEq(t, `{
+		"loc": {"type": "Point", "coordinates": [15.9, 52.1]},
+		"area": {"type": "Polygon", "coordinates": [`+ring+`]},
+		"many": {"type": "MultiPolygon", "coordinates": [[`+ring+`]]}
+	}`, string(b))
+
+	var decoded geoPlace
+	require.NoError(t, ndgo.Unmarshal(b, &decoded))
+	require.Equal(t, *place.Loc, *decoded.Loc)
+	require.Len(t, decoded.Area[0], 5, "ring is closed")
+	require.Equal(t, decoded.Area, decoded.Many[0])
+
+	var p ndgo.Point
+	require.NoError(t, json.Unmarshal([]byte(`"{\"type\":\"Point\",\"coordinates\":[1.5,2]}"`), &p))
+	require.Equal(t, ndgo.Point{Lng: 1.5, Lat: 2}, p)
+	require.Error(t, json.Unmarshal([]byte(`{"type":"Polygon","coordinates":[]}`), &p))
+
+	_, err = ndgo.Marshal(ndgo.Point{Lng: 52.1, Lat: 115.9})
+	require.Error(t, err, "latitude out of range")
+	_, err = ndgo.Marshal(ndgo.Polygon{{{Lng: 0, Lat: 0}, {Lng: 1, Lat: 1}}})
+	require.Error(t, err, "ring too short")
+}
+
+func TestGeoQueryHelpers(t *testing.T) {
+	p := ndgo.Point{Lng: 15.9, Lat: 52.1}
+	triangle := ndgo.Polygon{{{Lng: 0, Lat: 0}, {Lng: 1, Lat: 0}, {Lng: 1, Lat: 1}}}
+
+	fx, err := ndgo.Query{}.Near("loc", p, 1000)
+	require.NoError(t, err)
+	require.Equal(t, "near(loc, [15.9, 52.1], 1000)", fx)
+	fx, err = ndgo.Query{}.Within("loc", triangle)
+	require.NoError(t, err)
+	require.Equal(t, "within(loc, [[[0, 0], [1, 0], [1, 1], [0, 0]]])", fx)
+	fx, err = ndgo.Query{}.Contains("area", p)
+	require.NoError(t, err)
+	require.Equal(t, "contains(area, [15.9, 52.1])", fx)
+	fx, err = ndgo.Query{}.Intersects("area", triangle)
+	require.NoError(t, err)
+	require.Equal(t, "intersects(area, [[[0, 0], [1, 0], [1, 1], [0, 0]]])", fx)
+	_, err = ndgo.Query{}.Near("loc", ndgo.Point{Lat: 91}, 1)
+	require.Error(t, err)
+
+	set, err := ndgo.Query{}.SetGeo("0x1", "loc", p)
+	require.NoError(t, err)
+	require.Equal(t, ndgo.SetRDF(`<0x1> <loc> "{\"type\":\"Point\",\"coordinates\":[15.9,52.1]}"^^<geo:geojson> .`+"\n"), set)
+	require.NoError(t, set.Validate())
+}
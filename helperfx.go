@@ -1,9 +1,5 @@
 package ndgo
 
-import (
-	"encoding/json"
-)
-
 // --------------------------------------- exported ---------------------------------------
 
 // Unsafe collects helpers, which require knowledge of how they work to operate correctly
@@ -39,7 +35,7 @@ func (Unsafe) FlattenRespToArray(bytes []byte) []byte {
 func interfaces2Bytes(jsonMutations ...interface{}) []byte {
 	allBytes := make([][]byte, len(jsonMutations))
 	for i := 0; i < len(jsonMutations); i++ {
-		jsonBytes, err := json.Marshal(jsonMutations[i])
+		jsonBytes, err := Marshal(jsonMutations[i])
 		if err != nil {
 			panic(err.Error()) // should never happen
 		}
@@ -0,0 +1,99 @@
+package ndgo
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// --------------------------------------- lang string ---------------------------------------
+
+// LangString holds values of a string predicate with @lang, by language. Untagged value has key "".
+// As a struct field, Marshal encodes it to `pred@lang` keys, i.e. `name@en`, and Unmarshal decodes them,
+// so query it with `name@*`, or Query{}.Lang("name").
+type LangString map[string]string
+
+var langStringType = reflect.TypeOf(LangString{})
+
+// UnmarshalJSON decodes object of values by language, or a string as untagged value, i.e. when querying `name`
+func (v *LangString) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*v = LangString{"": s}
+		return nil
+	}
+	var m map[string]string
+	if err := json.Unmarshal(data, &m); err != nil {
+		return fmt.Errorf("ndgo: lang string must be string or object: %w", err)
+	}
+	*v = m
+	return nil
+}
+
+// Pick returns value of the first language in langs, which is set. Like in queries, "." means untagged value,
+// or any value if there's none, so it should be last. Without langs, it returns untagged value.
+func (v LangString) Pick(langs ...string) (string, bool) {
+	if len(langs) == 0 {
+		langs = []string{""}
+	}
+	for _, lang := range langs {
+		if lang != "." {
+			if val, ok := v[lang]; ok {
+				return val, true
+			}
+			continue
+		}
+		if val, ok := v[""]; ok {
+			return val, true
+		}
+		if keys := v.langs(); len(keys) > 0 {
+			return v[keys[0]], true
+		}
+	}
+	return "", false
+}
+
+// NQuads returns N-Quads setting pred of node uid to all values
+func (v LangString) NQuads(uid, pred string) SetRDF {
+	var b strings.Builder
+	for _, lang := range v.langs() {
+		b.WriteString(NQuad{Subject: uid, Predicate: pred, ObjectValue: v[lang], Lang: lang}.String())
+		b.WriteString("\n")
+	}
+	return SetRDF(b.String())
+}
+
+// langs returns sorted languages
+func (v LangString) langs() []string {
+	res := make([]string, 0, len(v))
+	for lang := range v {
+		res = append(res, lang)
+	}
+	sort.Strings(res)
+	return res
+}
+
+func langKey(pred, lang string) string {
+	if lang == "" {
+		return pred
+	}
+	return pred + "@" + lang
+}
+
+// --------------------------------------- query helpers ---------------------------------------
+
+// Lang returns pred with language preference list, i.e. Query{}.Lang("name", "en", "de", ".") is `name@en:de:.`,
+// which returns the first language found, in order. Without langs, it's `name@*`, which returns all languages.
+func (Query) Lang(pred string, langs ...string) string {
+	if len(langs) == 0 {
+		return pred + "@*"
+	}
+	return pred + "@" + strings.Join(langs, ":")
+}
+
+// SetPredLang Usage: ndgo.Query{}.SetPredLang(uid, predicate, "en", value)
+func (Query) SetPredLang(uid, predicate, lang, value string) SetRDF {
+	return SetRDF(NQuad{Subject: uid, Predicate: predicate, ObjectValue: value, Lang: lang}.String() + "\n")
+}
@@ -0,0 +1,63 @@
+package ndgo_test
+
+import (
+	"testing"
+
+	"github.com/ppp225/ndgo/v5"
+	"github.com/stretchr/testify/require"
+)
+
+type langPerson struct {
+	UID  string          `json:"uid,omitempty"`
+	Name ndgo.LangString `json:"name,omitempty"`
+}
+
+func TestLangStringMarshal(t *testing.T) {
+	p := langPerson{UID: "_:p", Name: ndgo.LangString{"": "Alice", "en": "Alice", "de": "Alicia"}}
+	b, err := ndgo.Marshal(p)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"uid": "_:p", "name": "Alice", "name@en": "Alice", "name@de": "Alicia"}`, string(b))
+
+	b, err = ndgo.Marshal(langPerson{UID: "_:p"})
+	require.NoError(t, err)
+	require.JSONEq(t, `{"uid": "_:p"}`, string(b))
+
+	var decoded langPerson
+	require.NoError(t, ndgo.Unmarshal([]byte(`{"uid": "0x1", "name": "Alice", "name@de": "Alicia", "name@en": "Alice"}`), &decoded))
+	require.Equal(t, p.Name, decoded.Name)
+
+	decoded = langPerson{}
+	require.NoError(t, ndgo.Unmarshal([]byte(`{"name": "x"}`), &decoded), "i.e. `name: name@en:.`")
+	require.Equal(t, ndgo.LangString{"": "x"}, decoded.Name)
+}
+
+func TestLangStringPick(t *testing.T) {
+	name := ndgo.LangString{"de": "Alicia", "pl": "Alicja"}
+	var testData = []struct {
+		langs []string
+		out   string
+		ok    bool
+	}{
+		{langs: nil, out: "", ok: false},
+		{langs: []string{"en", "de"}, out: "Alicia", ok: true},
+		{langs: []string{"en"}, out: "", ok: false},
+		{langs: []string{"en", "."}, out: "Alicia", ok: true},
+		{langs: []string{"pl", "de"}, out: "Alicja", ok: true},
+	}
+	for _, tt := range testData {
+		out, ok := name.Pick(tt.langs...)
+		require.Equal(t, tt.out, out, tt.langs)
+		require.Equal(t, tt.ok, ok, tt.langs)
+	}
+	name[""] = "Alice"
+	out, _ := name.Pick("en", ".")
+	require.Equal(t, "Alice", out)
+}
+
+func TestLangQueryHelpers(t *testing.T) {
+	require.Equal(t, "name@*", ndgo.Query{}.Lang("name"))
+	require.Equal(t, "name@en:de:.", ndgo.Query{}.Lang("name", "en", "de", "."))
+	require.Equal(t, ndgo.SetRDF(`<0x1> <name> "Alicia"@de .`+"\n"), ndgo.Query{}.SetPredLang("0x1", "name", "de", "Alicia"))
+	require.Equal(t, ndgo.SetRDF("_:p <name> \"A\" .\n_:p <name> \"\\\"B\\\"\"@en .\n"),
+		ndgo.LangString{"en": `"B"`, "": "A"}.NQuads("_:p", "name"))
+}
@@ -0,0 +1,389 @@
+package ndgo
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// --------------------------------------- marshal ---------------------------------------
+
+// Marshal encodes v to dgraph json like json.Marshal, and also encodes facets declared with `dgraph` struct tags.
+// A field tagged `dgraph:"pred,facet=name"` holds facet name of predicate pred, i.e. json key `pred|name`.
+// Without pred, i.e. `dgraph:",facet=since"`, it's a facet of the edge through which the struct is reached,
+// so edge facets are declared once on the child struct, including children of list edges.
+// Facets of scalar list predicates, i.e. [string], are slices or maps, indexed like the list values.
+// Zero facets are omitted. LangString fields are encoded to `pred@lang` keys. Zero DateTime fields are omitted,
+// as null would delete the predicate in delete mutations. Seti, Deletei and DoSeti use Marshal.
+func Marshal(v interface{}) ([]byte, error) {
+	if v == nil || !needsWalk(reflect.TypeOf(v)) {
+		return json.Marshal(v)
+	}
+	val, err := encodeValue(reflect.ValueOf(v), "")
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(val)
+}
+
+// Unmarshal decodes dgraph json into v like json.Unmarshal, and also decodes facets declared with `dgraph` struct tags.
+// See Marshal. Query facets with @facets, i.e. `friend @facets(since) { uid }`, so they're returned under `pred|facet` keys.
+// LangString fields are decoded from `pred@lang` keys, i.e. when querying `name@*`.
+func Unmarshal(data []byte, v interface{}) error {
+	if err := json.Unmarshal(data, v); err != nil {
+		return err
+	}
+	if !needsWalk(reflect.TypeOf(v)) {
+		return nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var tree interface{}
+	if err := dec.Decode(&tree); err != nil {
+		return err
+	}
+	return decodeValue(reflect.ValueOf(v), tree, "")
+}
+
+// --------------------------------------- struct info ---------------------------------------
+
+// facetField is a struct field, either a predicate with json key name, or a facet
+type facetField struct {
+	index     []int
+	name      string
+	omitEmpty bool
+	pred      string // facet predicate, empty means the edge through which the struct is reached
+	facet     string // facet name, empty for predicate fields
+}
+
+type structInfo struct {
+	fields []facetField
+	walk   bool // the struct, or any struct reachable from it, has facet, LangString or DateTime fields
+}
+
+var structInfos sync.Map // reflect.Type -> *structInfo
+
+// needsWalk reports whether values of type t contain facet, LangString or DateTime fields, which json doesn't handle
+func needsWalk(t reflect.Type) bool {
+	return needsWalkSeen(t, map[reflect.Type]bool{})
+}
+
+func needsWalkSeen(t reflect.Type, seen map[reflect.Type]bool) bool {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || seen[t] {
+		return false
+	}
+	if cached, ok := structInfos.Load(t); ok {
+		return cached.(*structInfo).walk
+	}
+	seen[t] = true
+	info := &structInfo{fields: structFields(t, nil)}
+	for _, f := range info.fields {
+		ft := t.FieldByIndex(f.index).Type
+		if f.facet != "" || ft == langStringType || ft == dateTimeType || needsWalkSeen(ft, seen) {
+			info.walk = true
+			break
+		}
+	}
+	// recursive types are only cached by their outermost call, when the result is final
+	if len(seen) == 1 || info.walk {
+		structInfos.Store(t, info)
+	}
+	delete(seen, t)
+	return info.walk
+}
+
+func getStructInfo(t reflect.Type) *structInfo {
+	needsWalk(t)
+	if cached, ok := structInfos.Load(t); ok {
+		return cached.(*structInfo)
+	}
+	return &structInfo{fields: structFields(t, nil)}
+}
+
+// structFields returns exported fields of t following json naming, with fields of embedded structs promoted
+func structFields(t reflect.Type, index []int) (fields []facetField) {
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		idx := append(append([]int{}, index...), i)
+		jsonTag := sf.Tag.Get("json")
+		name, opts := jsonTag, ""
+		if comma := strings.IndexByte(jsonTag, ','); comma >= 0 {
+			name, opts = jsonTag[:comma], jsonTag[comma+1:]
+		}
+		if tag, ok := sf.Tag.Lookup("dgraph"); ok {
+			if pred, facet, ok := parseFacetTag(tag); ok {
+				fields = append(fields, facetField{index: idx, pred: pred, facet: facet})
+				continue
+			}
+		}
+		if name == "-" && opts == "" {
+			continue
+		}
+		ft := sf.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if sf.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+			fields = append(fields, structFields(ft, idx)...)
+			continue
+		}
+		if sf.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+		fields = append(fields, facetField{index: idx, name: name, omitEmpty: strings.Contains(","+opts+",", ",omitempty,")})
+	}
+	return fields
+}
+
+// parseFacetTag parses `pred,facet=name` dgraph tag
+func parseFacetTag(tag string) (pred, facet string, ok bool) {
+	parts := strings.Split(tag, ",")
+	for _, p := range parts[1:] {
+		if strings.HasPrefix(p, "facet=") {
+			facet = strings.TrimPrefix(p, "facet=")
+		}
+	}
+	return parts[0], facet, facet != ""
+}
+
+// fieldByIndex is like reflect.Value.FieldByIndex, but reports false for fields of nil embedded pointers
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+// --------------------------------------- encode ---------------------------------------
+
+var jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+
+// encodeFacets converts v to value for json.Marshal, with structs containing facets converted to maps.
+// pred is the predicate through which v is reached.
+func encodeValue(v reflect.Value, pred string) (interface{}, error) {
+	if !v.IsValid() {
+		return nil, nil
+	}
+	if v.Type().Implements(jsonMarshalerType) || !needsWalk(v.Type()) {
+		return v.Interface(), nil
+	}
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil, nil
+		}
+		return encodeValue(v.Elem(), pred)
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return nil, nil
+		}
+		res := make([]interface{}, v.Len())
+		for i := range res {
+			var err error
+			if res[i], err = encodeValue(v.Index(i), pred); err != nil {
+				return nil, err
+			}
+		}
+		return res, nil
+	case reflect.Struct:
+		obj := map[string]interface{}{}
+		for _, f := range getStructInfo(v.Type()).fields {
+			fv, ok := fieldByIndex(v, f.index)
+			if !ok {
+				continue
+			}
+			if f.facet != "" {
+				facetPred := f.pred
+				if facetPred == "" {
+					facetPred = pred
+				}
+				if facetPred == "" || isEmptyValue(fv) {
+					continue
+				}
+				obj[facetPred+"|"+f.facet] = encodeFacetValue(fv)
+				continue
+			}
+			if f.omitEmpty && isEmptyValue(fv) || isZeroDateTime(fv) {
+				continue
+			}
+			if fv.Type() == langStringType {
+				for lang, val := range fv.Interface().(LangString) {
+					obj[langKey(f.name, lang)] = val
+				}
+				continue
+			}
+			val, err := encodeValue(fv, f.name)
+			if err != nil {
+				return nil, fmt.Errorf("ndgo: marshal %s: %w", f.name, err)
+			}
+			obj[f.name] = val
+		}
+		return obj, nil
+	}
+	return v.Interface(), nil
+}
+
+// isZeroDateTime reports whether v is a zero DateTime, or a pointer to it
+func isZeroDateTime(v reflect.Value) bool {
+	if v.Kind() == reflect.Ptr && !v.IsNil() {
+		v = v.Elem()
+	}
+	return v.Type() == dateTimeType && v.Interface().(DateTime).IsZero()
+}
+
+// encodeFacetValue returns facet value. Slices and maps are facets of scalar list values, which dgraph keys by index.
+func encodeFacetValue(v reflect.Value) interface{} {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			break
+		}
+		res := make(map[string]interface{}, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			if !isEmptyValue(v.Index(i)) {
+				res[strconv.Itoa(i)] = v.Index(i).Interface()
+			}
+		}
+		return res
+	}
+	return v.Interface()
+}
+
+// isEmptyValue reports whether v is empty in the sense of json omitempty, or is a zero struct, i.e. time.Time{}
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	case reflect.Struct:
+		return v.IsZero()
+	}
+	return false
+}
+
+// --------------------------------------- decode ---------------------------------------
+
+// decodeFacets sets facet fields of v from json tree, already decoded into v by json.Unmarshal
+func decodeValue(v reflect.Value, tree interface{}, pred string) error {
+	if tree == nil || !needsWalk(v.Type()) {
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return decodeValue(v.Elem(), tree, pred)
+	case reflect.Slice, reflect.Array:
+		list, ok := tree.([]interface{})
+		if !ok {
+			return nil
+		}
+		for i := 0; i < v.Len() && i < len(list); i++ {
+			if err := decodeValue(v.Index(i), list[i], pred); err != nil {
+				return err
+			}
+		}
+	case reflect.Struct:
+		obj, ok := tree.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		for _, f := range getStructInfo(v.Type()).fields {
+			fv, ok := fieldByIndex(v, f.index)
+			if !ok {
+				continue
+			}
+			if f.facet == "" && fv.Type() == langStringType {
+				decodeLangString(fv, obj, f.name)
+				continue
+			}
+			if f.facet == "" {
+				if err := decodeValue(fv, obj[f.name], f.name); err != nil {
+					return err
+				}
+				continue
+			}
+			facetPred := f.pred
+			if facetPred == "" {
+				facetPred = pred
+			}
+			raw, ok := obj[facetPred+"|"+f.facet]
+			if !ok || facetPred == "" {
+				continue
+			}
+			if err := decodeFacetValue(fv, raw); err != nil {
+				return fmt.Errorf("ndgo: unmarshal facet %s|%s: %w", facetPred, f.facet, err)
+			}
+		}
+	}
+	return nil
+}
+
+// decodeFacetValue sets v to raw facet value. Index keyed maps of scalar list facets are converted to slices.
+func decodeFacetValue(v reflect.Value, raw interface{}) error {
+	if byIndex, ok := raw.(map[string]interface{}); ok && v.Kind() == reflect.Slice {
+		indexes := make([]int, 0, len(byIndex))
+		for key := range byIndex {
+			i, err := strconv.Atoi(key)
+			if err != nil {
+				return fmt.Errorf("list facet index %q is not a number", key)
+			}
+			indexes = append(indexes, i)
+		}
+		sort.Ints(indexes)
+		list := make([]interface{}, 0, len(indexes))
+		for _, i := range indexes {
+			for len(list) < i {
+				list = append(list, nil)
+			}
+			list = append(list, byIndex[strconv.Itoa(i)])
+		}
+		raw = list
+	}
+	b, err := json.Marshal(raw)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v.Addr().Interface())
+}
+
+// decodeLangString adds values of `pred@lang` keys of obj to LangString v
+func decodeLangString(v reflect.Value, obj map[string]interface{}, pred string) {
+	for key, raw := range obj {
+		val, ok := raw.(string)
+		if !ok || !strings.HasPrefix(key, pred+"@") {
+			continue
+		}
+		if v.IsNil() {
+			v.Set(reflect.ValueOf(LangString{}))
+		}
+		v.SetMapIndex(reflect.ValueOf(strings.TrimPrefix(key, pred+"@")), reflect.ValueOf(val))
+	}
+}
@@ -0,0 +1,91 @@
+package ndgo_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ppp225/ndgo/v5"
+	"github.com/stretchr/testify/require"
+)
+
+type facetPerson struct {
+	UID       string         `json:"uid,omitempty"`
+	Name      string         `json:"name,omitempty"`
+	NameOrig  bool           `json:"-" dgraph:"name,facet=original"`
+	Nicks     []string       `json:"nick,omitempty"`
+	NickKinds []string       `json:"-" dgraph:"nick,facet=kind"`
+	Friends   []facetFriend  `json:"friend,omitempty"`
+	Best      *facetFriend   `json:"best,omitempty"`
+	Other     map[string]int `json:"other,omitempty"`
+}
+
+type facetFriend struct {
+	UID   string    `json:"uid,omitempty"`
+	Since time.Time `json:"-" dgraph:",facet=since"`
+	Close bool      `json:"-" dgraph:",facet=close"`
+}
+
+func TestMarshalFacets(t *testing.T) {
+	since := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	p := facetPerson{
+		UID:       "_:p",
+		Name:      "Alice",
+		NameOrig:  true,
+		Nicks:     []string{"al", "ali"},
+		NickKinds: []string{"", "long"},
+		Friends:   []facetFriend{{UID: "0x1", Since: since, Close: true}, {UID: "0x2"}},
+		Best:      &facetFriend{UID: "0x3", Close: true},
+	}
+	b, err := ndgo.Marshal(p)
+	require.NoError(t, err)
+	require.JSONEq(t, `{
+		"uid": "_:p",
+		"name": "Alice",
+		"name|original": true,
+		"nick": ["al", "ali"],
+		"nick|kind": {"1": "long"},
+		"friend": [{"uid": "0x1", "friend|since": "2020-01-02T03:04:05Z", "friend|close": true}, {"uid": "0x2"}],
+		"best": {"uid": "0x3", "best|close": true}
+	}`, string(b))
+
+	var decoded facetPerson
+	require.NoError(t, ndgo.Unmarshal(b, &decoded))
+	require.Equal(t, p, decoded)
+}
+
+func TestMarshalWithoutFacets(t *testing.T) {
+	obj := struct {
+		Name string `json:"name"`
+		Age  int    `json:"age,omitempty"`
+	}{Name: "x"}
+	b, err := ndgo.Marshal(&obj)
+	require.NoError(t, err)
+	require.Equal(t, `{"name":"x"}`, string(b))
+
+	b, err = ndgo.Marshal([]interface{}{obj, facetFriend{UID: "0x1", Close: true}})
+	require.NoError(t, err)
+	require.JSONEq(t, `[{"name":"x"},{"uid":"0x1"}]`, string(b), "edge facets of root nodes have no predicate")
+}
+
+func TestUnmarshalQueryFacets(t *testing.T) {
+	resp := []byte(`{"q":[{
+		"name": "Bob",
+		"nick": ["b", "bobby"],
+		"nick|kind": {"1": "long"},
+		"friend": [{"uid": "0x1", "friend|close": true}, {"uid": "0x2", "friend|since": "2021-05-06T00:00:00Z"}]
+	}]}`)
+	var decode struct {
+		Q []facetPerson `json:"q"`
+	}
+	require.NoError(t, ndgo.Unmarshal(resp, &decode))
+	require.Len(t, decode.Q, 1)
+	p := decode.Q[0]
+	require.Equal(t, []string{"", "long"}, p.NickKinds)
+	require.Len(t, p.Friends, 2)
+	require.True(t, p.Friends[0].Close)
+	require.True(t, p.Friends[0].Since.IsZero())
+	require.Equal(t, time.Date(2021, 5, 6, 0, 0, 0, 0, time.UTC), p.Friends[1].Since)
+
+	err := ndgo.Unmarshal([]byte(`{"q":[{"nick|kind":{"x":"a"}}]}`), &decode)
+	require.Error(t, err)
+}
@@ -0,0 +1,428 @@
+// Package migrate provides versioned dgraph schema and data migrations built on ndgo - github.com/ppp225/ndgo/migrate
+package migrate
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dgraph-io/dgo/v210"
+	"github.com/dgraph-io/dgo/v210/protos/api"
+	log "github.com/ppp225/lvlog"
+	"github.com/ppp225/ndgo/v5"
+)
+
+// --------------------------------------- reserved schema ---------------------------------------
+
+// Reserved predicates and types used to track applied migrations in the graph itself
+const (
+	TypeMigration = "NdgoMigration"
+	TypeLock      = "NdgoMigrationLock"
+
+	PredVersion   = "ndgo.migration.version"
+	PredName      = "ndgo.migration.name"
+	PredAppliedAt = "ndgo.migration.appliedAt"
+	PredLock      = "ndgo.migration.lock"
+	PredOwner     = "ndgo.migration.owner"
+	PredLockedAt  = "ndgo.migration.lockedAt"
+
+	lockKey = "migrate"
+)
+
+var reservedSchema = `
+	<` + PredVersion + `>: int @index(int) @upsert .
+	<` + PredName + `>: string .
+	<` + PredAppliedAt + `>: datetime .
+	<` + PredLock + `>: string @index(exact) @upsert .
+	<` + PredOwner + `>: string .
+	<` + PredLockedAt + `>: datetime .
+
+	type ` + TypeMigration + ` {
+		` + PredVersion + `
+		` + PredName + `
+		` + PredAppliedAt + `
+	}
+
+	type ` + TypeLock + ` {
+		` + PredLock + `
+		` + PredOwner + `
+		` + PredLockedAt + `
+	}
+`
+
+// --------------------------------------- errors ---------------------------------------
+
+var (
+	// ErrLocked is returned, when another instance holds the migration lock
+	ErrLocked = errors.New("ndgo/migrate: migrations are locked by another instance")
+	// ErrUnknownVersion is returned, when target version is not a registered migration
+	ErrUnknownVersion = errors.New("ndgo/migrate: unknown migration version")
+	// ErrNoDown is returned, when a migration has to be reverted, but it has no Down step
+	ErrNoDown = errors.New("ndgo/migrate: migration has no down step")
+)
+
+// --------------------------------------- definitions ---------------------------------------
+
+// Migration is a single versioned change of the database.
+// Versions must be unique and greater than 0. They are applied in ascending order.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      Step
+	Down    Step
+}
+
+// Step describes what is run when a migration is applied or reverted.
+// Alter operations are run first, then Data is run in a single Txn, which also records the migration.
+type Step struct {
+	Alter []*api.Operation
+	Data  func(txn *ndgo.Txn) error
+}
+
+// Schema is a helper, which creates an Alter operation from schema string
+func Schema(schema string) *api.Operation {
+	return &api.Operation{Schema: schema}
+}
+
+// DropAttr is a helper, which creates an Alter operation dropping given predicate
+func DropAttr(predicate string) *api.Operation {
+	return &api.Operation{DropAttr: predicate}
+}
+
+// isEmpty reports whether step does nothing
+func (v Step) isEmpty() bool {
+	return len(v.Alter) == 0 && v.Data == nil
+}
+
+// Direction describes if a planned migration is applied or reverted
+type Direction string
+
+// Available directions
+const (
+	Up   Direction = "up"
+	Down Direction = "down"
+)
+
+// Planned is a single migration, which is (or would be, when DryRun) run
+type Planned struct {
+	Version   int64
+	Name      string
+	Direction Direction
+}
+
+// Record is an applied migration, as stored in the graph
+type Record struct {
+	UID       string    `json:"uid"`
+	Version   int64     `json:"version"`
+	Name      string    `json:"name"`
+	AppliedAt time.Time `json:"appliedAt"`
+}
+
+// --------------------------------------- migrator ---------------------------------------
+
+// Migrator runs migrations against dgraph.
+// Set DryRun to only plan migrations, without altering the database.
+type Migrator struct {
+	DryRun bool
+	// Owner identifies this instance in the lock node. Defaults to hostname and pid.
+	Owner string
+
+	dg         *dgo.Dgraph
+	migrations []Migration
+}
+
+// New creates a Migrator. It validates, that versions are unique and positive.
+func New(dg *dgo.Dgraph, migrations ...Migration) (*Migrator, error) {
+	sorted := make([]Migration, len(migrations))
+	copy(sorted, migrations)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Version < sorted[j].Version })
+	for i, m := range sorted {
+		if m.Version <= 0 {
+			return nil, fmt.Errorf("ndgo/migrate: migration %q has invalid version %d, must be > 0", m.Name, m.Version)
+		}
+		if i > 0 && sorted[i-1].Version == m.Version {
+			return nil, fmt.Errorf("ndgo/migrate: duplicate migration version %d (%q and %q)", m.Version, sorted[i-1].Name, m.Name)
+		}
+		if m.Up.isEmpty() {
+			return nil, fmt.Errorf("ndgo/migrate: migration %d %q has no up step", m.Version, m.Name)
+		}
+	}
+	hostname, _ := os.Hostname()
+	return &Migrator{
+		Owner:      fmt.Sprintf("%s:%d", hostname, os.Getpid()),
+		dg:         dg,
+		migrations: sorted,
+	}, nil
+}
+
+// Latest returns highest registered migration version, or 0 if there are none
+func (v *Migrator) Latest() int64 {
+	if len(v.migrations) == 0 {
+		return 0
+	}
+	return v.migrations[len(v.migrations)-1].Version
+}
+
+// Up applies all pending migrations
+func (v *Migrator) Up(ctx context.Context) ([]Planned, error) {
+	return v.To(ctx, v.Latest())
+}
+
+// Down reverts applied migrations down to (but excluding) target version. Use 0 to revert all.
+// Unlike To, it never applies pending migrations, and returns an error, if target is above current version.
+func (v *Migrator) Down(ctx context.Context, target int64) ([]Planned, error) {
+	return v.migrate(ctx, target, Down)
+}
+
+// To migrates database to target version: applies pending migrations with version <= target
+// and reverts applied migrations with version > target. Use 0 to revert all.
+// Returns migrations which were run, or would be run, if DryRun is set.
+func (v *Migrator) To(ctx context.Context, target int64) (done []Planned, err error) {
+	return v.migrate(ctx, target, "")
+}
+
+// migrate migrates database to target version, only in given direction, if set
+func (v *Migrator) migrate(ctx context.Context, target int64, only Direction) (done []Planned, err error) {
+	if target != 0 && v.find(target) == nil {
+		return nil, fmt.Errorf("%w: %d", ErrUnknownVersion, target)
+	}
+	if !v.DryRun {
+		if err = v.ensureSchema(ctx); err != nil {
+			return nil, err
+		}
+		var unlock func() error
+		if unlock, err = v.Lock(ctx); err != nil {
+			return nil, err
+		}
+		defer func() {
+			if uerr := unlock(); uerr != nil && err == nil {
+				err = uerr
+			}
+		}()
+	}
+
+	applied, err := v.Applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if only == Down && target > current(applied) {
+		return nil, fmt.Errorf("ndgo/migrate: down target %d is above current version %d", target, current(applied))
+	}
+	plan, err := v.plan(applied, target, only)
+	if err != nil || v.DryRun {
+		return plan, err
+	}
+
+	for _, p := range plan {
+		log.Infof("ndgo/migrate: %s %d %s\n", p.Direction, p.Version, p.Name)
+		m := v.find(p.Version)
+		if p.Direction == Up {
+			err = v.apply(ctx, m)
+		} else {
+			err = v.revert(ctx, m, applied)
+		}
+		if err != nil {
+			return done, fmt.Errorf("ndgo/migrate: %s %d %q failed: %w", p.Direction, p.Version, p.Name, err)
+		}
+		done = append(done, p)
+	}
+	return done, nil
+}
+
+// Applied returns migrations recorded in the graph, ordered by version
+func (v *Migrator) Applied(ctx context.Context) ([]Record, error) {
+	txn := ndgo.NewTxn(ctx, v.dg.NewReadOnlyTxn())
+	defer txn.Discard()
+	resp, err := txn.Query(fmt.Sprintf(`
+	{
+	  q(func: type(%s)) {
+	    uid
+	    version: %s
+	    name: %s
+	    appliedAt: %s
+	  }
+	}
+	`, TypeMigration, PredVersion, PredName, PredAppliedAt))
+	if err != nil {
+		return nil, err
+	}
+	var decode struct {
+		Q []Record `json:"q"`
+	}
+	if err := json.Unmarshal(resp.GetJson(), &decode); err != nil {
+		return nil, err
+	}
+	sort.Slice(decode.Q, func(i, j int) bool { return decode.Q[i].Version < decode.Q[j].Version })
+	return decode.Q, nil
+}
+
+// current returns highest applied version, or 0 if there are none
+func current(applied []Record) int64 {
+	if len(applied) == 0 {
+		return 0
+	}
+	return applied[len(applied)-1].Version
+}
+
+// plan computes which migrations need to be run to reach target version, only in given direction, if set
+func (v *Migrator) plan(applied []Record, target int64, only Direction) ([]Planned, error) {
+	isApplied := make(map[int64]bool, len(applied))
+	for _, r := range applied {
+		isApplied[r.Version] = true
+	}
+	var plan []Planned
+	// revert in descending order first
+	for i := len(v.migrations) - 1; i >= 0; i-- {
+		m := v.migrations[i]
+		if m.Version > target && isApplied[m.Version] {
+			if m.Down.isEmpty() {
+				return nil, fmt.Errorf("%w: %d %q", ErrNoDown, m.Version, m.Name)
+			}
+			plan = append(plan, Planned{Version: m.Version, Name: m.Name, Direction: Down})
+		}
+	}
+	for _, m := range v.migrations {
+		if only != Down && m.Version <= target && !isApplied[m.Version] {
+			plan = append(plan, Planned{Version: m.Version, Name: m.Name, Direction: Up})
+		}
+	}
+	return plan, nil
+}
+
+func (v *Migrator) find(version int64) *Migration {
+	for i := range v.migrations {
+		if v.migrations[i].Version == version {
+			return &v.migrations[i]
+		}
+	}
+	return nil
+}
+
+func (v *Migrator) ensureSchema(ctx context.Context) error {
+	return v.dg.Alter(ctx, &api.Operation{Schema: reservedSchema})
+}
+
+// apply runs Up step and records migration in the same txn as data migration
+func (v *Migrator) apply(ctx context.Context, m *Migration) error {
+	if err := v.alter(ctx, m.Up.Alter); err != nil {
+		return err
+	}
+	txn := ndgo.NewTxn(ctx, v.dg.NewTxn())
+	defer txn.Discard()
+	if m.Up.Data != nil {
+		if err := m.Up.Data(txn); err != nil {
+			return err
+		}
+	}
+	nq := ndgo.SetRDF(strings.Join([]string{
+		ndgo.NQuad{Subject: "_:m", Predicate: PredVersion, ObjectValue: strconv.FormatInt(m.Version, 10), Datatype: ndgo.XSInt}.String(),
+		ndgo.NQuad{Subject: "_:m", Predicate: PredName, ObjectValue: m.Name}.String(),
+		ndgo.NQuad{Subject: "_:m", Predicate: PredAppliedAt, ObjectValue: time.Now().UTC().Format(time.RFC3339), Datatype: ndgo.XSDateTime}.String(),
+		ndgo.NQuad{Subject: "_:m", Predicate: "dgraph.type", ObjectValue: TypeMigration}.String(),
+	}, "\n"))
+	if _, err := nq.Run(txn); err != nil {
+		return err
+	}
+	return txn.Commit()
+}
+
+// revert runs Down step and removes migration record in the same txn as data migration
+func (v *Migrator) revert(ctx context.Context, m *Migration, applied []Record) error {
+	txn := ndgo.NewTxn(ctx, v.dg.NewTxn())
+	defer txn.Discard()
+	if m.Down.Data != nil {
+		if err := m.Down.Data(txn); err != nil {
+			return err
+		}
+	}
+	var del ndgo.DeleteRDF
+	for _, r := range applied {
+		if r.Version == m.Version {
+			del += ndgo.Query{}.DeleteNode(r.UID)
+		}
+	}
+	if del != "" {
+		if _, err := del.Run(txn); err != nil {
+			return err
+		}
+	}
+	if err := txn.Commit(); err != nil {
+		return err
+	}
+	return v.alter(ctx, m.Down.Alter)
+}
+
+func (v *Migrator) alter(ctx context.Context, ops []*api.Operation) error {
+	for _, op := range ops {
+		if err := v.dg.Alter(ctx, op); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// --------------------------------------- lock ---------------------------------------
+
+// Lock acquires the migration lock, so only one instance migrates at a time.
+// Returns ErrLocked, if lock is held by another instance. Call returned func to release the lock.
+// To and Up/Down acquire the lock automatically.
+func (v *Migrator) Lock(ctx context.Context) (unlock func() error, err error) {
+	txn := ndgo.NewTxn(ctx, v.dg.NewTxn())
+	defer txn.Discard()
+	q := fmt.Sprintf(`{ l as var(func: eq(%s, %q)) }`, PredLock, lockKey)
+	nq := strings.Join([]string{
+		ndgo.NQuad{Subject: "_:lock", Predicate: PredLock, ObjectValue: lockKey}.String(),
+		ndgo.NQuad{Subject: "_:lock", Predicate: PredOwner, ObjectValue: v.Owner}.String(),
+		ndgo.NQuad{Subject: "_:lock", Predicate: PredLockedAt, ObjectValue: time.Now().UTC().Format(time.RFC3339), Datatype: ndgo.XSDateTime}.String(),
+		ndgo.NQuad{Subject: "_:lock", Predicate: "dgraph.type", ObjectValue: TypeLock}.String(),
+	}, "\n")
+	resp, err := txn.DoSetb(q, "@if(eq(len(l), 0))", nil, []byte(nq))
+	if err != nil {
+		return nil, err
+	}
+	uid, ok := resp.Uids["lock"]
+	if !ok {
+		return nil, ErrLocked
+	}
+	if err = txn.Commit(); err != nil {
+		if errors.Is(err, dgo.ErrAborted) { // another instance raced us to the lock
+			return nil, ErrLocked
+		}
+		return nil, err
+	}
+	return func() error {
+		txn := ndgo.NewTxn(context.Background(), v.dg.NewTxn())
+		defer txn.Discard()
+		del := ndgo.Query{}.DeleteNode(uid)
+		if _, err := del.Run(txn); err != nil {
+			return err
+		}
+		return txn.Commit()
+	}, nil
+}
+
+// ForceUnlock removes the migration lock regardless of owner. Use it to recover after a crashed instance.
+func (v *Migrator) ForceUnlock(ctx context.Context) error {
+	if err := v.ensureSchema(ctx); err != nil {
+		return err
+	}
+	txn := ndgo.NewTxn(ctx, v.dg.NewTxn())
+	defer txn.Discard()
+	q := fmt.Sprintf(`{ l as var(func: eq(%s, %q)) }`, PredLock, lockKey)
+	_, err := txn.Do(&api.Request{
+		Query: q,
+		Mutations: []*api.Mutation{
+			{DelNquads: []byte(`uid(l) * * .`)},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	return txn.Commit()
+}
@@ -0,0 +1,158 @@
+package migrate_test
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"testing"
+
+	"github.com/dgraph-io/dgo/v210"
+	"github.com/dgraph-io/dgo/v210/protos/api"
+	log "github.com/ppp225/lvlog"
+	"github.com/ppp225/ndgo/v5"
+	"github.com/ppp225/ndgo/v5/migrate"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+)
+
+const (
+	dbIP          = "localhost:9080"
+	predicateName = "testMigrateName"
+)
+
+// dgNewClient creates new *dgo.Dgraph Client
+func dgNewClient() *dgo.Dgraph {
+	ip := dbIP
+	dat, err := ioutil.ReadFile("../db.cfg")
+	if err == nil {
+		ip = string(dat)
+	}
+	conn, err := grpc.Dial(ip, grpc.WithInsecure())
+	if err != nil {
+		log.Fatal(err)
+	}
+	return dgo.NewDgraphClient(
+		api.NewDgraphClient(conn),
+	)
+}
+
+func testMigrations() []migrate.Migration {
+	return []migrate.Migration{
+		{
+			Version: 2,
+			Name:    `seed "data" \`, // quotes and backslashes must be escaped in the record
+			Up: migrate.Step{Data: func(txn *ndgo.Txn) error {
+				_, err := ndgo.Query{}.SetPred("_:n", predicateName, "seeded").Run(txn)
+				return err
+			}},
+			Down: migrate.Step{Data: func(txn *ndgo.Txn) error {
+				_, err := txn.Do(&api.Request{
+					Query:     `{ n as var(func: eq(` + predicateName + `, "seeded")) }`,
+					Mutations: []*api.Mutation{{DelNquads: []byte(`uid(n) * * .`)}},
+				})
+				return err
+			}},
+		},
+		{
+			Version: 1,
+			Name:    "schema",
+			Up:      migrate.Step{Alter: []*api.Operation{migrate.Schema(`<` + predicateName + `>: string @index(exact) .`)}},
+			Down:    migrate.Step{Alter: []*api.Operation{migrate.DropAttr(predicateName)}},
+		},
+	}
+}
+
+func TestNewValidation(t *testing.T) {
+	up := migrate.Step{Alter: []*api.Operation{migrate.Schema(`<a>: string .`)}}
+	var testData = []struct {
+		migrations []migrate.Migration
+		err        bool
+	}{
+		{migrations: nil},
+		{migrations: testMigrations()},
+		{migrations: []migrate.Migration{{Version: 0, Name: "zero", Up: up}}, err: true},
+		{migrations: []migrate.Migration{{Version: 1, Name: "a", Up: up}, {Version: 1, Name: "b", Up: up}}, err: true},
+		{migrations: []migrate.Migration{{Version: 1, Name: "empty"}}, err: true},
+	}
+
+	for i, tt := range testData {
+		m, err := migrate.New(nil, tt.migrations...)
+		if tt.err {
+			require.Error(t, err, "Test i=%d", i)
+			continue
+		}
+		require.NoError(t, err, "Test i=%d", i)
+		require.Equal(t, int64(len(tt.migrations)), m.Latest(), "Test i=%d", i)
+	}
+}
+
+func TestMigrateUpDown(t *testing.T) {
+	dg := dgNewClient()
+	ctx := context.Background()
+	m, err := migrate.New(dg, testMigrations()...)
+	require.NoError(t, err)
+	require.NoError(t, m.ForceUnlock(ctx))
+
+	// dry run
+	m.DryRun = true
+	plan, err := m.Up(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []migrate.Planned{
+		{Version: 1, Name: "schema", Direction: migrate.Up},
+		{Version: 2, Name: `seed "data" \`, Direction: migrate.Up},
+	}, plan)
+	applied, err := m.Applied(ctx)
+	require.NoError(t, err)
+	require.Len(t, applied, 0, "dry run should not apply anything")
+
+	// up
+	m.DryRun = false
+	done, err := m.Up(ctx)
+	require.NoError(t, err)
+	require.Len(t, done, 2)
+	done, err = m.Up(ctx)
+	require.NoError(t, err)
+	require.Len(t, done, 0, "everything should be applied already")
+
+	txn := ndgo.NewTxn(ctx, dg.NewReadOnlyTxn())
+	resp, err := ndgo.Query{}.GetPredExpandType("q", "eq", predicateName, "seeded", "", "", "uid", "_all_").Run(txn)
+	txn.Discard()
+	require.NoError(t, err)
+	var decode struct {
+		Q []struct {
+			UID string `json:"uid"`
+		} `json:"q"`
+	}
+	require.NoError(t, json.Unmarshal(resp.GetJson(), &decode))
+	require.Len(t, decode.Q, 1, "data migration should have run")
+
+	// lock
+	unlock, err := m.Lock(ctx)
+	require.NoError(t, err)
+	_, err = m.Lock(ctx)
+	require.ErrorIs(t, err, migrate.ErrLocked)
+	_, err = m.Up(ctx)
+	require.ErrorIs(t, err, migrate.ErrLocked)
+	require.NoError(t, unlock())
+
+	// down
+	done, err = m.Down(ctx, 0)
+	require.NoError(t, err)
+	require.Equal(t, []migrate.Planned{
+		{Version: 2, Name: `seed "data" \`, Direction: migrate.Down},
+		{Version: 1, Name: "schema", Direction: migrate.Down},
+	}, done)
+	applied, err = m.Applied(ctx)
+	require.NoError(t, err)
+	require.Len(t, applied, 0, "everything should be reverted")
+
+	// down never applies pending migrations
+	done, err = m.To(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, done, 1)
+	_, err = m.Down(ctx, 2)
+	require.EqualError(t, err, "ndgo/migrate: down target 2 is above current version 1")
+	done, err = m.Down(ctx, 0)
+	require.NoError(t, err)
+	require.Equal(t, []migrate.Planned{{Version: 1, Name: "schema", Direction: migrate.Down}}, done)
+}
@@ -23,9 +23,16 @@ func Debug() {
 // Txn is a dgo.Txn wrapper with additional diagnostic data
 // Helps with Queries, by providing abstractions for dgraph Query and Mutation
 type Txn struct {
-	diag diag
-	ctx  context.Context
-	txn  *dgo.Txn
+	diag  diag
+	ctx   context.Context
+	txn   *dgo.Txn
+	cache *QueryCache
+	// mutated are predicates mutated by this Txn, tracked when cache is set
+	mutated map[string]bool
+	// onCommit are called after successful Commit
+	onCommit []func()
+	// created are uids of nodes created by uncommitted mutations
+	created map[string]bool
 }
 
 // NewTxn creates new Txn (with ctx)
@@ -54,9 +61,29 @@ func (v *Txn) Commit() (err error) {
 	t := time.Now()
 	err = v.txn.Commit(v.ctx)
 	v.diag.addNW(t)
+	if err == nil {
+		v.invalidateMutated()
+		for _, fx := range v.onCommit {
+			fx()
+		}
+		v.onCommit = nil
+	}
 	return
 }
 
+// trackCreated records uids of nodes created by mutations, which aren't committed yet
+func (v *Txn) trackCreated(commitNow bool, resp *api.Response) {
+	if commitNow || len(resp.GetUids()) == 0 {
+		return
+	}
+	if v.created == nil {
+		v.created = map[string]bool{}
+	}
+	for _, uid := range resp.GetUids() {
+		v.created[uid] = true
+	}
+}
+
 // Do executes a query followed by one or more mutations.
 // Possible to run query without mutations, or vice versa
 func (v *Txn) Do(req *api.Request) (resp *api.Response, err error) {
@@ -68,6 +95,8 @@ func (v *Txn) Do(req *api.Request) (resp *api.Response, err error) {
 		return nil, err
 	}
 	v.diag.addDB(resp.Latency)
+	v.trackMutations(req.CommitNow, req.Mutations...)
+	v.trackCreated(req.CommitNow, resp)
 	log.Tracef("Resp: %s\n---\n", resp.String())
 	return
 }
@@ -82,12 +111,19 @@ func (v *Txn) Mutate(mu *api.Mutation) (resp *api.Response, err error) {
 		return nil, err
 	}
 	v.diag.addDB(resp.Latency)
+	v.trackMutations(mu.CommitNow, mu)
+	v.trackCreated(mu.CommitNow, resp)
 	log.Tracef("Mutate Resp: %s\n---\n", resp.String())
 	return
 }
 
 // Query performs dgraph query
 func (v *Txn) Query(q string) (resp *api.Response, err error) {
+	resp, gen := v.cachedQuery(q, nil)
+	if resp != nil {
+		log.Tracef("Query JSON (cached): %s\n", q)
+		return resp, nil
+	}
 	t := time.Now()
 	log.Tracef("Query JSON: %s\n", q)
 	resp, err = v.txn.Query(v.ctx, q)
@@ -96,12 +132,18 @@ func (v *Txn) Query(q string) (resp *api.Response, err error) {
 		return nil, err
 	}
 	v.diag.addDB(resp.Latency)
+	v.cacheResponse(q, nil, resp, gen)
 	log.Tracef("Query Resp: %s\n---\n", resp.String())
 	return
 }
 
 // QueryWithVars performs dgraph query with vars
 func (v *Txn) QueryWithVars(q string, vars map[string]string) (resp *api.Response, err error) {
+	resp, gen := v.cachedQuery(q, vars)
+	if resp != nil {
+		log.Tracef("QueryWithVars JSON (cached): %s %s\n", q, vars)
+		return resp, nil
+	}
 	t := time.Now()
 	log.Tracef("QueryWithVars JSON: %s %s\n", q, vars)
 	resp, err = v.txn.QueryWithVars(v.ctx, q, vars)
@@ -110,17 +152,48 @@ func (v *Txn) QueryWithVars(q string, vars map[string]string) (resp *api.Respons
 		return nil, err
 	}
 	v.diag.addDB(resp.Latency)
+	v.cacheResponse(q, vars, resp, gen)
 	log.Tracef("QueryWithVars Resp: %s\n---\n", resp.String())
 	return
 }
 
+// QueryRDF performs dgraph query, which returns resp.Rdf instead of resp.Json. Use ParseRDFResponse to decode it.
+func (v *Txn) QueryRDF(q string) (resp *api.Response, err error) {
+	t := time.Now()
+	log.Tracef("QueryRDF: %s\n", q)
+	resp, err = v.txn.QueryRDF(v.ctx, q)
+	v.diag.addNW(t)
+	if err != nil {
+		return nil, err
+	}
+	v.diag.addDB(resp.Latency)
+	log.Tracef("QueryRDF Resp: %s\n---\n", resp.String())
+	return
+}
+
+// QueryRDFWithVars performs dgraph query with vars, which returns resp.Rdf instead of resp.Json
+func (v *Txn) QueryRDFWithVars(q string, vars map[string]string) (resp *api.Response, err error) {
+	t := time.Now()
+	log.Tracef("QueryRDFWithVars: %s %s\n", q, vars)
+	resp, err = v.txn.QueryRDFWithVars(v.ctx, q, vars)
+	v.diag.addNW(t)
+	if err != nil {
+		return nil, err
+	}
+	v.diag.addDB(resp.Latency)
+	log.Tracef("QueryRDFWithVars Resp: %s\n---\n", resp.String())
+	return
+}
+
 // --------------------------------------- diag ---------------------------------------
 
 // diag contains diagnostic data for timing the transaction
 // dbms - database total time - which sums all dgraph resp.Latency and
 // nwms - newtwork total time - which is the total time until response
+// hits - queries served from QueryCache, which add to neither
 type diag struct {
 	dbms, nwms float64
+	hits       int
 }
 
 func (v *diag) addDB(latency *api.Latency) {
@@ -0,0 +1,298 @@
+package ndgo
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// --------------------------------------- paths ---------------------------------------
+
+// Path is a sequence of nodes connected by edges, as returned by shortest path and recurse queries
+type Path struct {
+	UIDs   []string
+	Edges  []PathEdge               // Edges[i] connects UIDs[i] and UIDs[i+1]
+	Nodes  []map[string]interface{} // fields of UIDs[i], if requested
+	Weight float64                  // total weight of Edges
+}
+
+// PathEdge is an edge of a Path. Weight is its weight facet, or 1 if there's none.
+type PathEdge struct {
+	From   string
+	Pred   string
+	To     string
+	Weight float64
+}
+
+func (v *Path) add(from, pred, to string, weight float64) {
+	v.UIDs = append(v.UIDs, to)
+	v.Edges = append(v.Edges, PathEdge{From: from, Pred: pred, To: to, Weight: weight})
+	v.Weight += weight
+}
+
+// edgeSelection writes edges, with weight facet if any
+func edgeSelection(b *strings.Builder, indent string, edges []string, weight string) {
+	for _, edge := range edges {
+		b.WriteString(indent + edge)
+		if weight != "" {
+			fmt.Fprintf(b, " @facets(%s)", weight)
+		}
+		b.WriteString("\n")
+	}
+}
+
+// edgeWeight returns weight facet of edge pred in child node, or 1
+func edgeWeight(child map[string]interface{}, pred, weight string) (float64, error) {
+	if weight == "" {
+		return 1, nil
+	}
+	raw, ok := child[pred+"|"+weight]
+	if !ok {
+		return 1, nil
+	}
+	n, ok := raw.(json.Number)
+	if !ok {
+		return 0, fmt.Errorf("ndgo: weight facet %s|%s is not a number: %v", pred, weight, raw)
+	}
+	return n.Float64()
+}
+
+// children returns nodes linked by edge, which dgraph returns as object or list
+func children(node map[string]interface{}, edge string) (res []map[string]interface{}) {
+	switch val := node[edge].(type) {
+	case map[string]interface{}:
+		return []map[string]interface{}{val}
+	case []interface{}:
+		for _, item := range val {
+			if child, ok := item.(map[string]interface{}); ok {
+				res = append(res, child)
+			}
+		}
+	}
+	return res
+}
+
+func decodeTree(data []byte) (map[string]interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var res map[string]interface{}
+	err := dec.Decode(&res)
+	return res, err
+}
+
+// --------------------------------------- shortest ---------------------------------------
+
+// ShortestOptions configures Query{}.Shortest
+type ShortestOptions struct {
+	From  string   // uid, or uid(var)
+	To    string   // uid, or uid(var)
+	Edges []string // predicates followed, reverse edges allowed, i.e. `~friend`
+	// Weight is a numeric facet of Edges used as edge weight. Without it, every edge weighs 1.
+	Weight    string
+	NumPaths  int      // k shortest paths, default 1
+	Depth     int      // max number of edges, unlimited if 0
+	MinWeight float64  // min path weight, unset if 0
+	MaxWeight float64  // max path weight, unset if 0
+	Fields    []string // predicates of path nodes returned in Path.Nodes
+}
+
+// ShortestQuery is a shortest path query. Run it with Run, or use it as QueryDQL.
+type ShortestQuery struct {
+	QueryDQL
+	opts ShortestOptions
+}
+
+// Shortest Usage: paths, err := ndgo.Query{}.Shortest(ndgo.ShortestOptions{From: uid1, To: uid2, Edges: []string{"friend"}}).Run(txn)
+func (Query) Shortest(opts ShortestOptions) ShortestQuery {
+	args := []string{"from: " + opts.From, "to: " + opts.To}
+	if opts.NumPaths > 1 {
+		args = append(args, fmt.Sprintf("numpaths: %d", opts.NumPaths))
+	}
+	if opts.Depth > 0 {
+		args = append(args, fmt.Sprintf("depth: %d", opts.Depth))
+	}
+	if opts.MinWeight != 0 {
+		args = append(args, "minweight: "+strconv.FormatFloat(opts.MinWeight, 'f', -1, 64))
+	}
+	if opts.MaxWeight != 0 {
+		args = append(args, "maxweight: "+strconv.FormatFloat(opts.MaxWeight, 'f', -1, 64))
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "{\n  path as shortest(%s) {\n", strings.Join(args, ", "))
+	edgeSelection(&b, "    ", opts.Edges, opts.Weight)
+	b.WriteString("  }\n")
+	if len(opts.Fields) > 0 {
+		fmt.Fprintf(&b, "  nodes(func: uid(path)) {\n    uid\n    %s\n  }\n", strings.Join(opts.Fields, "\n    "))
+	}
+	b.WriteString("}")
+	return ShortestQuery{QueryDQL: QueryDQL(b.String()), opts: opts}
+}
+
+// Run returns shortest paths, ordered by weight. There are none, if To isn't reachable.
+func (v ShortestQuery) Run(t *Txn) ([]Path, error) {
+	resp, err := v.QueryDQL.Run(t)
+	if err != nil {
+		return nil, err
+	}
+	return decodeShortest(resp.GetJson(), v.opts)
+}
+
+func decodeShortest(data []byte, opts ShortestOptions) ([]Path, error) {
+	tree, err := decodeTree(data)
+	if err != nil {
+		return nil, err
+	}
+	nodes := map[string]map[string]interface{}{}
+	for _, item := range children(tree, "nodes") {
+		uid, _ := item["uid"].(string)
+		nodes[uid] = item
+	}
+	var paths []Path
+	for _, node := range children(tree, "_path_") {
+		uid, _ := node["uid"].(string)
+		path := Path{UIDs: []string{uid}}
+	next:
+		for {
+			for _, edge := range opts.Edges {
+				linked := children(node, edge)
+				if len(linked) == 0 {
+					continue
+				}
+				child := linked[0]
+				weight, err := edgeWeight(child, edge, opts.Weight)
+				if err != nil {
+					return nil, err
+				}
+				to, _ := child["uid"].(string)
+				path.add(uid, edge, to, weight)
+				node, uid = child, to
+				continue next
+			}
+			break
+		}
+		if len(opts.Fields) > 0 {
+			path.Nodes = make([]map[string]interface{}, len(path.UIDs))
+			for i, uid := range path.UIDs {
+				path.Nodes[i] = nodes[uid]
+			}
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+// --------------------------------------- recurse ---------------------------------------
+
+// RecurseOptions configures Query{}.Recurse
+type RecurseOptions struct {
+	Func   string   // root function, i.e. `uid(0x1)`
+	Edges  []string // predicates followed, reverse edges allowed, i.e. `~friend`
+	Weight string   // numeric facet of Edges, returned as edge weight. Without it, every edge weighs 1.
+	Fields []string // predicates of nodes returned in TreeNode.Fields
+	Depth  int      // max depth, counting the root level, unlimited if 0
+	Loop   bool     // revisit nodes, requires Depth
+}
+
+// TreeNode is a node of a recurse query result
+type TreeNode struct {
+	UID      string
+	Pred     string  // edge from parent, empty for roots
+	Weight   float64 // weight of edge from parent
+	Fields   map[string]interface{}
+	Children []*TreeNode
+}
+
+// RecurseQuery is a @recurse query. Run it with Run, or use it as QueryDQL.
+type RecurseQuery struct {
+	QueryDQL
+	opts RecurseOptions
+}
+
+// Recurse Usage: roots, err := ndgo.Query{}.Recurse(ndgo.RecurseOptions{Func: "uid(0x1)", Edges: []string{"friend"}, Depth: 3}).Run(txn)
+func (Query) Recurse(opts RecurseOptions) RecurseQuery {
+	args := fmt.Sprintf("loop: %t", opts.Loop)
+	if opts.Depth > 0 {
+		args = fmt.Sprintf("depth: %d, %s", opts.Depth, args)
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "{\n  q(func: %s) @recurse(%s) {\n    uid\n", opts.Func, args)
+	for _, field := range opts.Fields {
+		b.WriteString("    " + field + "\n")
+	}
+	edgeSelection(&b, "    ", opts.Edges, opts.Weight)
+	b.WriteString("  }\n}")
+	return RecurseQuery{QueryDQL: QueryDQL(b.String()), opts: opts}
+}
+
+// Run returns trees of nodes reached from roots
+func (v RecurseQuery) Run(t *Txn) ([]*TreeNode, error) {
+	resp, err := v.QueryDQL.Run(t)
+	if err != nil {
+		return nil, err
+	}
+	return decodeRecurse(resp.GetJson(), v.opts)
+}
+
+func decodeRecurse(data []byte, opts RecurseOptions) ([]*TreeNode, error) {
+	tree, err := decodeTree(data)
+	if err != nil {
+		return nil, err
+	}
+	var roots []*TreeNode
+	for _, item := range children(tree, "q") {
+		root, err := decodeTreeNode(item, "", 0, opts)
+		if err != nil {
+			return nil, err
+		}
+		roots = append(roots, root)
+	}
+	return roots, nil
+}
+
+func decodeTreeNode(item map[string]interface{}, pred string, weight float64, opts RecurseOptions) (*TreeNode, error) {
+	node := &TreeNode{Pred: pred, Weight: weight, Fields: map[string]interface{}{}}
+	node.UID, _ = item["uid"].(string)
+	for _, field := range opts.Fields {
+		if val, ok := item[field]; ok {
+			node.Fields[field] = val
+		}
+	}
+	for _, edge := range opts.Edges {
+		for _, child := range children(item, edge) {
+			w, err := edgeWeight(child, edge, opts.Weight)
+			if err != nil {
+				return nil, err
+			}
+			c, err := decodeTreeNode(child, edge, w, opts)
+			if err != nil {
+				return nil, err
+			}
+			node.Children = append(node.Children, c)
+		}
+	}
+	return node, nil
+}
+
+// Paths returns paths from v to every leaf below it, in depth-first order
+func (v *TreeNode) Paths() (paths []Path) {
+	var walk func(node *TreeNode, path Path)
+	walk = func(node *TreeNode, path Path) {
+		if len(node.Children) == 0 {
+			paths = append(paths, path)
+			return
+		}
+		for _, child := range node.Children {
+			next := Path{
+				UIDs:   path.UIDs[:len(path.UIDs):len(path.UIDs)],
+				Edges:  path.Edges[:len(path.Edges):len(path.Edges)],
+				Weight: path.Weight,
+			}
+			next.add(node.UID, child.Pred, child.UID, child.Weight)
+			walk(child, next)
+		}
+	}
+	walk(v, Path{UIDs: []string{v.UID}})
+	return paths
+}
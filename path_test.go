@@ -0,0 +1,94 @@
+package ndgo_test
+
+import (
+	"testing"
+
+	"github.com/ppp225/ndgo/v5"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPathQueries(t *testing.T) {
+	shortest := ndgo.Query{}.Shortest(ndgo.ShortestOptions{
+		From:      "0x1",
+		To:        "0x2",
+		Edges:     []string{"friend", "~friend"},
+		Weight:    "weight",
+		NumPaths:  2,
+		Depth:     3,
+		MaxWeight: 10.5,
+		Fields:    []string{"name"},
+	})
+	require.Equal(t, ndgo.QueryDQL(`{
+  path as shortest(from: 0x1, to: 0x2, numpaths: 2, depth: 3, maxweight: 10.5) {
+    friend @facets(weight)
+    ~friend @facets(weight)
+  }
+  nodes(func: uid(path)) {
+    uid
+    name
+  }
+}`), shortest.QueryDQL)
+
+	recurse := ndgo.Query{}.Recurse(ndgo.RecurseOptions{
+		Func:   "uid(0x1)",
+		Edges:  []string{"friend"},
+		Fields: []string{"name"},
+		Depth:  3,
+	})
+	require.Equal(t, ndgo.QueryDQL(`{
+  q(func: uid(0x1)) @recurse(depth: 3, loop: false) {
+    uid
+    name
+    friend
+  }
+}`), recurse.QueryDQL)
+
+	for _, q := range []ndgo.QueryDQL{shortest.QueryDQL, recurse.QueryDQL} {
+		_, err := ndgo.ParseDQL(string(q))
+		require.NoError(t, err)
+	}
+}
+
+func TestTxnShortestRecurse(t *testing.T) {
+	dg := dgNewClient()
+	defer setupTeardown(dg)()
+	txn := ndgo.NewTxnWithoutContext(dg.NewTxn())
+	defer txn.Discard()
+	uid1, uid2, uid3, uid4 := populateDBComplex(txn, t)
+	_, err := setEdgeRDF(uid2, uid3).Run(txn)
+	require.NoError(t, err)
+
+	paths, err := ndgo.Query{}.Shortest(ndgo.ShortestOptions{
+		From:   uid1,
+		To:     uid3,
+		Edges:  []string{predicateEdge},
+		Fields: []string{predicateName},
+	}).Run(txn)
+	require.NoError(t, err)
+	require.Len(t, paths, 1)
+	require.Equal(t, []string{uid1, uid3}, paths[0].UIDs)
+	require.Equal(t, []ndgo.PathEdge{{From: uid1, Pred: predicateEdge, To: uid3, Weight: 1}}, paths[0].Edges)
+	require.Equal(t, float64(1), paths[0].Weight)
+	require.Equal(t, thirdName, paths[0].Nodes[1][predicateName])
+
+	paths, err = ndgo.Query{}.Shortest(ndgo.ShortestOptions{From: uid3, To: uid1, Edges: []string{predicateEdge}}).Run(txn)
+	require.NoError(t, err)
+	require.Empty(t, paths)
+
+	roots, err := ndgo.Query{}.Recurse(ndgo.RecurseOptions{
+		Func:   "uid(" + uid1 + ")",
+		Edges:  []string{predicateEdge},
+		Fields: []string{predicateName},
+		Depth:  5,
+	}).Run(txn)
+	require.NoError(t, err)
+	require.Len(t, roots, 1)
+	require.Equal(t, firstName, roots[0].Fields[predicateName])
+	require.Len(t, roots[0].Children, 3)
+	var leaves []string
+	for _, p := range roots[0].Paths() {
+		require.Equal(t, uid1, p.UIDs[0])
+		leaves = append(leaves, p.UIDs[len(p.UIDs)-1])
+	}
+	require.Contains(t, leaves, uid4, "loop: false visits uid3 once, through uid1 or uid2")
+}
@@ -70,7 +70,17 @@ func (v QueryDQL) Run(t *Txn) (resp *api.Response, err error) {
 	return t.Query(string(res))
 }
 
-// Join allows to join multiple json Query of same type
+// RunRDF makes a dgraph db query, which returns resp.Rdf instead of resp.Json. Use ParseRDFResponse to decode it.
+func (v QueryDQL) RunRDF(t *Txn) (resp *api.Response, err error) {
+	res := make([]byte, len(v)+2)
+	res[0] = '['
+	copy(res[1:], v)
+	res[len(res)-1] = ']'
+	return t.QueryRDF(string(res))
+}
+
+// Join allows to join multiple json Query of same type.
+// Use Merge to combine queries into a single document, i.e. when they use query variables.
 func (v QueryDQL) Join(json QueryDQL) QueryDQL {
 	return v + "," + json
 }
@@ -0,0 +1,588 @@
+package ndgo
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// --------------------------------------- n-quad ---------------------------------------
+
+// NQuad represents a single dgraph N-Quad, i.e. `<0x1> <name> "Keanu"@en (since=2006) .`
+type NQuad struct {
+	// Subject is uid (0x1), blank node (_:new), uid(v) or val(v) variable, or * wildcard
+	Subject   string
+	Predicate string
+	// ObjectID is set, when object is a node (same forms as Subject)
+	ObjectID string
+	// ObjectValue is set, when object is a literal
+	ObjectValue string
+	Lang        string
+	Datatype    string
+	Facets      []Facet
+}
+
+// Facet is a key value pair attached to an edge or a value.
+// Value is one of string, int64, float64, bool or time.Time. When formatting, other numeric types are accepted too.
+type Facet struct {
+	Key   string
+	Value interface{}
+}
+
+// IsLiteral reports whether object of the N-Quad is a value, not a node
+func (v NQuad) IsLiteral() bool {
+	return v.ObjectID == ""
+}
+
+// Value returns literal converted according to its Datatype: int64, float64, bool, time.Time, json.RawMessage for geo, or string
+func (v NQuad) Value() (interface{}, error) {
+	switch v.Datatype {
+	case XSInt, "xs:integer":
+		return strconv.ParseInt(v.ObjectValue, 10, 64)
+	case XSFloat, "xs:double":
+		return strconv.ParseFloat(v.ObjectValue, 64)
+	case XSBoolean:
+		return strconv.ParseBool(v.ObjectValue)
+	case XSDateTime:
+		return time.Parse(time.RFC3339Nano, v.ObjectValue)
+	case GeoJSON:
+		return json.RawMessage(v.ObjectValue), nil
+	}
+	return v.ObjectValue, nil
+}
+
+// String formats N-Quad in dgraph RDF syntax, with trailing ` .` but without newline
+func (v NQuad) String() string {
+	var b strings.Builder
+	b.WriteString(formatNode(v.Subject))
+	if v.Predicate == "*" {
+		b.WriteString(" * ")
+	} else {
+		b.WriteString(" <")
+		b.WriteString(v.Predicate)
+		b.WriteString("> ")
+	}
+	if v.IsLiteral() {
+		b.WriteString(quoteRDF(v.ObjectValue))
+		if v.Lang != "" {
+			b.WriteByte('@')
+			b.WriteString(v.Lang)
+		}
+		if v.Datatype != "" {
+			b.WriteString("^^<")
+			b.WriteString(v.Datatype)
+			b.WriteByte('>')
+		}
+	} else {
+		b.WriteString(formatNode(v.ObjectID))
+	}
+	if len(v.Facets) > 0 {
+		b.WriteString(" (")
+		for i, f := range v.Facets {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			b.WriteString(f.Key)
+			b.WriteByte('=')
+			b.WriteString(formatFacetValue(f.Value))
+		}
+		b.WriteByte(')')
+	}
+	b.WriteString(" .")
+	return b.String()
+}
+
+// formatNode wraps uids in brackets, keeps blank nodes, variables and wildcards as is
+func formatNode(node string) string {
+	if node == "*" || strings.HasPrefix(node, "_:") || strings.HasPrefix(node, "uid(") || strings.HasPrefix(node, "val(") {
+		return node
+	}
+	return "<" + node + ">"
+}
+
+// formatFacetValue formats facet value of any numeric, bool or string kind, or time. Other values are quoted strings, per fmt.Sprint.
+func formatFacetValue(val interface{}) string {
+	v := reflect.ValueOf(val)
+	for v.Kind() == reflect.Ptr && !v.IsNil() {
+		v = v.Elem()
+	}
+	if v.IsValid() {
+		switch f := v.Interface().(type) {
+		case time.Time:
+			return f.Format(time.RFC3339Nano)
+		case DateTime:
+			return f.String()
+		case json.Number:
+			return f.String()
+		}
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return quoteRDF(v.String())
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(v.Uint(), 10)
+	case reflect.Float32:
+		return formatFacetFloat(v.Float(), 32)
+	case reflect.Float64:
+		return formatFacetFloat(v.Float(), 64)
+	}
+	return quoteRDF(fmt.Sprint(val))
+}
+
+// formatFacetFloat always writes a decimal point, i.e. `2.0`, as dgraph parses `2` as an int facet
+func formatFacetFloat(f float64, bitSize int) string {
+	s := strconv.FormatFloat(f, 'f', -1, bitSize)
+	if !strings.ContainsAny(s, ".IN") { // Inf and NaN as is
+		s += ".0"
+	}
+	return s
+}
+
+// quoteRDF quotes a literal, escaping it according to N-Quads spec
+func quoteRDF(s string) string {
+	var b strings.Builder
+	b.Grow(len(s) + 2)
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		default:
+			if r < 0x20 {
+				b.WriteString(`\u00`)
+				b.WriteString(strconv.FormatInt(int64(r)>>4, 16))
+				b.WriteString(strconv.FormatInt(int64(r)&0xf, 16))
+				continue
+			}
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// --------------------------------------- datatypes ---------------------------------------
+
+// RDF datatypes supported by dgraph
+const (
+	XSString   = "xs:string"
+	XSInt      = "xs:int"
+	XSFloat    = "xs:float"
+	XSBoolean  = "xs:boolean"
+	XSDateTime = "xs:dateTime"
+	XSPassword = "xs:password"
+	GeoJSON    = "geo:geojson"
+)
+
+// datatypeOf returns RDF datatype for dgraph schema type, or empty string for strings
+func datatypeOf(schemaType string) string {
+	switch schemaType {
+	case "int":
+		return XSInt
+	case "float":
+		return XSFloat
+	case "bool":
+		return XSBoolean
+	case "datetime":
+		return XSDateTime
+	case "geo":
+		return GeoJSON
+	case "password":
+		return XSPassword
+	}
+	return ""
+}
+
+// --------------------------------------- parse ---------------------------------------
+
+// RDFError is returned, when RDF can't be parsed or is invalid
+type RDFError struct {
+	Line   int
+	Column int
+	Msg    string
+}
+
+func (v *RDFError) Error() string {
+	return fmt.Sprintf("ndgo: rdf %d:%d: %s", v.Line, v.Column, v.Msg)
+}
+
+// ParseRDFResponse parses resp.Rdf, as returned by QueryRDF, into N-Quads
+func ParseRDFResponse(rdf []byte) ([]NQuad, error) {
+	return ParseNQuads(string(rdf))
+}
+
+// ParseNQuads parses dgraph RDF dialect: blank nodes, uid(v) and val(v) variables, * wildcards,
+// language tags, ^^ datatypes and facets in parentheses. Empty lines and # comments are skipped.
+// Errors are of type *RDFError, with line and column of the problem.
+func ParseNQuads(rdf string) (nquads []NQuad, err error) {
+	for i, line := range strings.Split(rdf, "\n") {
+		l := rdfLexer{s: line, line: i + 1}
+		l.skipSpace()
+		if l.pos == len(l.s) || l.peek() == '#' {
+			continue
+		}
+		nq, err := l.nquad()
+		if err != nil {
+			return nil, err
+		}
+		nquads = append(nquads, nq)
+	}
+	return nquads, nil
+}
+
+// Validate parses N-Quads and checks, that they are valid set mutations
+func (v SetRDF) Validate() error {
+	return validateNQuads(string(v), false)
+}
+
+// Validate parses N-Quads and checks, that they are valid delete mutations
+func (v DeleteRDF) Validate() error {
+	return validateNQuads(string(v), true)
+}
+
+func validateNQuads(rdf string, isDelete bool) error {
+	for i, line := range strings.Split(rdf, "\n") {
+		l := rdfLexer{s: line, line: i + 1}
+		l.skipSpace()
+		if l.pos == len(l.s) || l.peek() == '#' {
+			continue
+		}
+		nq, err := l.nquad()
+		if err != nil {
+			return err
+		}
+		if msg := nq.invalid(isDelete); msg != "" {
+			return &RDFError{Line: i + 1, Column: l.start + 1, Msg: msg}
+		}
+	}
+	return nil
+}
+
+// invalid returns reason why N-Quad can't be used in a set or delete mutation, or empty string
+func (v NQuad) invalid(isDelete bool) string {
+	switch {
+	case v.Subject == "*":
+		return "subject can't be a wildcard"
+	case strings.HasPrefix(v.Subject, "val("):
+		return "subject can't be a val() variable"
+	case !isDelete && v.Predicate == "*":
+		return "predicate wildcard is only allowed in delete mutations"
+	case !isDelete && v.ObjectID == "*":
+		return "object wildcard is only allowed in delete mutations"
+	case v.Predicate == "*" && v.ObjectID != "*":
+		return "predicate wildcard requires object wildcard, i.e. `<0x1> * * .`"
+	case isDelete && strings.HasPrefix(v.Subject, "_:"):
+		return "blank nodes can't be deleted"
+	}
+	return ""
+}
+
+// rdfLexer reads tokens of a single N-Quad
+type rdfLexer struct {
+	s     string
+	pos   int
+	line  int
+	start int // position of N-Quad in line
+}
+
+func (v *rdfLexer) errorf(format string, args ...interface{}) error {
+	return &RDFError{Line: v.line, Column: v.pos + 1, Msg: fmt.Sprintf(format, args...)}
+}
+
+// nquad reads a whole N-Quad, which must be the only thing on the line
+func (v *rdfLexer) nquad() (nq NQuad, err error) {
+	v.skipSpace()
+	v.start = v.pos
+	if nq.Subject, err = v.node(false); err != nil {
+		return nq, err
+	}
+	if nq.Predicate, err = v.predicate(); err != nil {
+		return nq, err
+	}
+	v.skipSpace()
+	if v.peek() == '"' {
+		if nq.ObjectValue, err = v.literal(); err != nil {
+			return nq, err
+		}
+		switch {
+		case v.peek() == '@':
+			v.pos++
+			if nq.Lang = v.word(); nq.Lang == "" {
+				return nq, v.errorf("empty language tag")
+			}
+		case strings.HasPrefix(v.s[v.pos:], "^^"):
+			v.pos += 2
+			if nq.Datatype, err = v.iri(); err != nil {
+				return nq, err
+			}
+		}
+	} else if nq.ObjectID, err = v.node(true); err != nil {
+		return nq, err
+	}
+	v.skipSpace()
+	if v.peek() == '(' {
+		if nq.Facets, err = v.facets(); err != nil {
+			return nq, err
+		}
+	}
+	v.skipSpace()
+	if v.peek() != '.' {
+		return nq, v.errorf("expected '.'")
+	}
+	v.pos++
+	v.skipSpace()
+	if v.pos < len(v.s) && v.peek() != '#' {
+		return nq, v.errorf("unexpected %q after '.'", v.s[v.pos:])
+	}
+	return nq, nil
+}
+
+func (v *rdfLexer) peek() byte {
+	if v.pos >= len(v.s) {
+		return 0
+	}
+	return v.s[v.pos]
+}
+
+func (v *rdfLexer) skipSpace() {
+	for v.pos < len(v.s) && isRDFSpace(v.s[v.pos]) {
+		v.pos++
+	}
+}
+
+// word reads until space or one of N-Quad delimiters
+func (v *rdfLexer) word() string {
+	start := v.pos
+	for v.pos < len(v.s) && !isRDFSpace(v.s[v.pos]) && !strings.ContainsRune("<>\"().,=^@", rune(v.s[v.pos])) {
+		v.pos++
+	}
+	return v.s[start:v.pos]
+}
+
+func isRDFSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+// isBlankNodeEnd reports whether blank node name ends before c, followed by next, or 0 at end of input:
+// at whitespace, facet list `(`, or final `.` of N-Quad, which may follow the name without space, i.e. `_:a <friend> _:b.`
+// Other dots are part of the name, i.e. `_:a.b`.
+func isBlankNodeEnd(c, next byte) bool {
+	switch c {
+	case '(':
+		return true
+	case '.':
+		return next == 0 || isRDFSpace(next)
+	}
+	return isRDFSpace(c)
+}
+
+// iri reads `<iri>` and returns it without brackets
+func (v *rdfLexer) iri() (string, error) {
+	v.skipSpace()
+	if v.peek() != '<' {
+		return "", v.errorf("expected '<'")
+	}
+	end := strings.IndexByte(v.s[v.pos:], '>')
+	if end < 0 {
+		return "", v.errorf("unclosed '<'")
+	}
+	iri := v.s[v.pos+1 : v.pos+end]
+	if iri == "" || strings.ContainsAny(iri, " \t\"") {
+		return "", v.errorf("invalid iri %q", iri)
+	}
+	v.pos += end + 1
+	return iri, nil
+}
+
+// predicate reads `<pred>` or `*`
+func (v *rdfLexer) predicate() (string, error) {
+	v.skipSpace()
+	if v.peek() == '*' {
+		v.pos++
+		return "*", nil
+	}
+	return v.iri()
+}
+
+// node reads `<uid>`, `_:blank`, `uid(v)`, `*`, or if object, also `val(v)`
+func (v *rdfLexer) node(isObject bool) (string, error) {
+	v.skipSpace()
+	rest := v.s[v.pos:]
+	switch {
+	case strings.HasPrefix(rest, "_:"):
+		v.pos += 2
+		start := v.pos
+		for v.pos < len(v.s) {
+			var next byte
+			if v.pos+1 < len(v.s) {
+				next = v.s[v.pos+1]
+			}
+			if isBlankNodeEnd(v.s[v.pos], next) {
+				break
+			}
+			v.pos++
+		}
+		name := v.s[start:v.pos]
+		if name == "" {
+			return "", v.errorf("empty blank node name")
+		}
+		return "_:" + name, nil
+	case strings.HasPrefix(rest, "uid("), strings.HasPrefix(rest, "val("):
+		if !isObject && strings.HasPrefix(rest, "val(") {
+			return "", v.errorf("val() variable is only allowed as object")
+		}
+		end := strings.IndexByte(rest, ')')
+		if end < 0 {
+			return "", v.errorf("unclosed variable")
+		}
+		if name := strings.TrimSpace(rest[4:end]); name == "" || strings.ContainsAny(name, " \t") {
+			return "", v.errorf("invalid variable name %q", name)
+		}
+		v.pos += end + 1
+		return rest[:end+1], nil
+	case strings.HasPrefix(rest, "*"):
+		v.pos++
+		return "*", nil
+	case strings.HasPrefix(rest, "<"):
+		return v.iri()
+	}
+	if isObject {
+		return "", v.errorf("expected object: <uid>, _:blank, uid(v), val(v), * or \"literal\"")
+	}
+	return "", v.errorf("expected subject: <uid>, _:blank, uid(v) or *")
+}
+
+// facets reads `(key=value, key2="value")`
+func (v *rdfLexer) facets() (facets []Facet, err error) {
+	v.pos++ // opening parenthesis
+	for {
+		v.skipSpace()
+		if v.peek() == ')' {
+			v.pos++
+			return facets, nil
+		}
+		if len(facets) > 0 {
+			if v.peek() != ',' {
+				return nil, v.errorf("expected ',' or ')' in facets")
+			}
+			v.pos++
+			v.skipSpace()
+		}
+		f := Facet{}
+		if f.Key = v.word(); f.Key == "" {
+			return nil, v.errorf("expected facet key")
+		}
+		v.skipSpace()
+		if v.peek() != '=' {
+			return nil, v.errorf("expected '=' after facet key %s", f.Key)
+		}
+		v.pos++
+		v.skipSpace()
+		if v.peek() == '"' {
+			if f.Value, err = v.literal(); err != nil {
+				return nil, err
+			}
+		} else {
+			start := v.pos
+			for v.pos < len(v.s) && !isRDFSpace(v.s[v.pos]) && v.s[v.pos] != ',' && v.s[v.pos] != ')' {
+				v.pos++
+			}
+			raw := v.s[start:v.pos]
+			if f.Value = parseFacetValue(raw); f.Value == nil {
+				v.pos = start
+				return nil, v.errorf("invalid facet value %q, strings must be quoted", raw)
+			}
+		}
+		facets = append(facets, f)
+	}
+}
+
+// parseFacetValue parses unquoted facet value: bool, int, float or datetime. Returns nil if invalid.
+func parseFacetValue(s string) interface{} {
+	if b, err := strconv.ParseBool(s); err == nil && (s == "true" || s == "false") {
+		return b
+	}
+	if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return i
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return f
+	}
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t
+		}
+	}
+	return nil
+}
+
+// literal reads a quoted literal and unescapes it
+func (v *rdfLexer) literal() (string, error) {
+	start := v.pos
+	v.pos++ // opening quote
+	var b strings.Builder
+	for v.pos < len(v.s) {
+		c := v.s[v.pos]
+		switch c {
+		case '"':
+			v.pos++
+			return b.String(), nil
+		case '\\':
+			if v.pos+1 >= len(v.s) {
+				return "", v.errorf("unfinished escape")
+			}
+			v.pos++
+			switch e := v.s[v.pos]; e {
+			case 't':
+				b.WriteByte('\t')
+			case 'b':
+				b.WriteByte('\b')
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			case 'f':
+				b.WriteByte('\f')
+			case '"', '\'', '\\':
+				b.WriteByte(e)
+			case 'u', 'U':
+				size := 4
+				if e == 'U' {
+					size = 8
+				}
+				if v.pos+size >= len(v.s) {
+					return "", v.errorf("unfinished unicode escape")
+				}
+				r, err := strconv.ParseUint(v.s[v.pos+1:v.pos+1+size], 16, 32)
+				if err != nil {
+					return "", v.errorf("invalid unicode escape")
+				}
+				b.WriteRune(rune(r))
+				v.pos += size
+			default:
+				return "", v.errorf("invalid escape '\\%c'", e)
+			}
+			v.pos++
+		default:
+			b.WriteByte(c)
+			v.pos++
+		}
+	}
+	v.pos = start
+	return "", v.errorf("unclosed literal")
+}
@@ -0,0 +1,213 @@
+package ndgo_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/ppp225/ndgo/v5"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNQuadString(t *testing.T) {
+	var testData = []struct {
+		in  ndgo.NQuad
+		out string
+	}{
+		{
+			in:  ndgo.NQuad{Subject: "0x1", Predicate: "name", ObjectValue: "Keanu"},
+			out: `<0x1> <name> "Keanu" .`,
+		},
+		{
+			in:  ndgo.NQuad{Subject: "_:new", Predicate: "name", ObjectValue: "K\"e\nanu\\", Lang: "en"},
+			out: `_:new <name> "K\"e\nanu\\"@en .`,
+		},
+		{
+			in:  ndgo.NQuad{Subject: "uid(v)", Predicate: "age", ObjectValue: "42", Datatype: ndgo.XSInt},
+			out: `uid(v) <age> "42"^^<xs:int> .`,
+		},
+		{
+			in: ndgo.NQuad{Subject: "0x1", Predicate: "friend", ObjectID: "0x2", Facets: []ndgo.Facet{
+				{Key: "close", Value: true},
+				{Key: "weight", Value: 0.5},
+				{Key: "since", Value: time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)},
+				{Key: "note", Value: "a"},
+				{Key: "n", Value: int64(3)},
+			}},
+			out: `<0x1> <friend> <0x2> (close=true, weight=0.5, since=2006-01-02T15:04:05Z, note="a", n=3) .`,
+		},
+		{
+			in: ndgo.NQuad{Subject: "0x1", Predicate: "friend", ObjectID: "0x2", Facets: []ndgo.Facet{
+				{Key: "a", Value: int32(-3)},
+				{Key: "b", Value: uint8(4)},
+				{Key: "c", Value: float32(0.1)},
+				{Key: "f", Value: 2.0},
+				{Key: "d", Value: json.Number("5")},
+				{Key: "e", Value: ndgo.DateTime{Time: time.Date(2006, 1, 2, 0, 0, 0, 0, time.UTC)}},
+			}},
+			out: `<0x1> <friend> <0x2> (a=-3, b=4, c=0.1, f=2.0, d=5, e=2006-01-02T00:00:00Z) .`,
+		},
+		{
+			in:  ndgo.NQuad{Subject: "0x1", Predicate: "friend", ObjectID: "*"},
+			out: `<0x1> <friend> * .`,
+		},
+		{
+			in:  ndgo.NQuad{Subject: "0x1", Predicate: "*", ObjectID: "*"},
+			out: `<0x1> * * .`,
+		},
+	}
+
+	for i, tt := range testData {
+		require.Equal(t, tt.out, tt.in.String(), "Test i=%d", i)
+	}
+}
+
+func TestParseRDFResponse(t *testing.T) {
+	nquads, err := ndgo.ParseRDFResponse([]byte(`<0x1> <name> "Ke\"anu!" .
+<0x1> <name> "Keanu"@en .
+<0x1> <age> "56"^^<xs:int> .
+<0x1> <born> "1964-09-02T00:00:00Z"^^<xs:dateTime> .
+
+<0x1> <friend> <0x2> .
+`))
+	require.NoError(t, err)
+	require.Equal(t, []ndgo.NQuad{
+		{Subject: "0x1", Predicate: "name", ObjectValue: `Ke"anu!`},
+		{Subject: "0x1", Predicate: "name", ObjectValue: "Keanu", Lang: "en"},
+		{Subject: "0x1", Predicate: "age", ObjectValue: "56", Datatype: ndgo.XSInt},
+		{Subject: "0x1", Predicate: "born", ObjectValue: "1964-09-02T00:00:00Z", Datatype: ndgo.XSDateTime},
+		{Subject: "0x1", Predicate: "friend", ObjectID: "0x2"},
+	}, nquads)
+
+	age, err := nquads[2].Value()
+	require.NoError(t, err)
+	require.Equal(t, int64(56), age)
+	born, err := nquads[3].Value()
+	require.NoError(t, err)
+	require.Equal(t, time.Date(1964, 9, 2, 0, 0, 0, 0, time.UTC), born)
+
+	var errData = []string{
+		`<0x1> <name> "unclosed .`,
+		`<0x1> <name> "a"`,
+		`<0x1> name "a" .`,
+		`<0x1> <name> "a" . extra`,
+	}
+	for i, in := range errData {
+		_, err := ndgo.ParseRDFResponse([]byte(in))
+		require.Error(t, err, "Test i=%d", i)
+	}
+}
+
+func TestQueryRDF(t *testing.T) {
+	dg := dgNewClient()
+	defer setupTeardown(dg)()
+
+	txn := ndgo.NewTxnWithoutContext(dg.NewTxn())
+	defer txn.Discard()
+	uid := populateDBSimple(txn, t)
+
+	resp, err := getPredUID("q", predicateName, firstName).RunRDF(txn)
+	require.NoError(t, err)
+	require.Empty(t, resp.GetJson())
+	nquads, err := ndgo.ParseRDFResponse(resp.GetRdf())
+	require.NoError(t, err)
+	require.Len(t, nquads, 1)
+	require.Equal(t, uid, nquads[0].Subject)
+
+	resp, err = txn.QueryRDFWithVars(`query q($name: string) { q(func: eq(`+predicateName+`, $name)) { `+predicateAttr+` } }`, map[string]string{"$name": firstName})
+	require.NoError(t, err)
+	nquads, err = ndgo.ParseRDFResponse(resp.GetRdf())
+	require.NoError(t, err)
+	require.Equal(t, []ndgo.NQuad{{Subject: uid, Predicate: predicateAttr, ObjectValue: firstAttr}}, nquads)
+	require.NotZero(t, txn.GetNetworkTime())
+}
+
+func TestParseNQuads(t *testing.T) {
+	nquads, err := ndgo.ParseNQuads(`
+		# comment
+		_:new <name> "Keanu"@en (since=2006-01-02T15:04:05Z, weight=0.5, n=3, close=true, note="a, b") .
+		uid(v) <friend> _:new .
+		<0x1> <score> val(s) . # trailing comment
+		<0x1> * * .
+		<0x1> <friend> * .
+		<0x1> <loc> "{\"type\":\"Point\",\"coordinates\":[1,2]}"^^<geo:geojson> .
+	`)
+	require.NoError(t, err)
+	require.Equal(t, []ndgo.NQuad{
+		{Subject: "_:new", Predicate: "name", ObjectValue: "Keanu", Lang: "en", Facets: []ndgo.Facet{
+			{Key: "since", Value: time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)},
+			{Key: "weight", Value: 0.5},
+			{Key: "n", Value: int64(3)},
+			{Key: "close", Value: true},
+			{Key: "note", Value: "a, b"},
+		}},
+		{Subject: "uid(v)", Predicate: "friend", ObjectID: "_:new"},
+		{Subject: "0x1", Predicate: "score", ObjectID: "val(s)"},
+		{Subject: "0x1", Predicate: "*", ObjectID: "*"},
+		{Subject: "0x1", Predicate: "friend", ObjectID: "*"},
+		{Subject: "0x1", Predicate: "loc", ObjectValue: `{"type":"Point","coordinates":[1,2]}`, Datatype: ndgo.GeoJSON},
+	}, nquads)
+
+	// blank node names may contain dots, but end at final dot and facets
+	blanks, err := ndgo.ParseNQuads("_:a.b <friend> _:c.d.\n_:a.b <friend> _:e(weight=1) .")
+	require.NoError(t, err)
+	require.Equal(t, []ndgo.NQuad{
+		{Subject: "_:a.b", Predicate: "friend", ObjectID: "_:c.d"},
+		{Subject: "_:a.b", Predicate: "friend", ObjectID: "_:e", Facets: []ndgo.Facet{{Key: "weight", Value: int64(1)}}},
+	}, blanks)
+
+	// round trip
+	for i, nq := range nquads {
+		parsed, err := ndgo.ParseNQuads(nq.String())
+		require.NoError(t, err, "Test i=%d", i)
+		require.Equal(t, nq, parsed[0], "Test i=%d", i)
+	}
+
+	var errData = []struct {
+		in     string
+		line   int
+		column int
+	}{
+		{in: `<0x1> <name> "unclosed .`, line: 1, column: 14},
+		{in: "\n\n  <0x1> <name> \"a\"", line: 3, column: 19},
+		{in: `val(v) <name> "a" .`, line: 1, column: 1},
+		{in: `<0x1> <name> "a" (since) .`, line: 1, column: 24},
+		{in: `<0x1> <name> "a" (note=a) .`, line: 1, column: 24},
+		{in: `<0x1> <name> "a"@ .`, line: 1, column: 18},
+		{in: `<0x1> <name> uid() .`, line: 1, column: 14},
+	}
+	for i, tt := range errData {
+		_, err := ndgo.ParseNQuads(tt.in)
+		require.Error(t, err, "Test i=%d", i)
+		var rdfErr *ndgo.RDFError
+		require.ErrorAs(t, err, &rdfErr, "Test i=%d", i)
+		require.Equal(t, tt.line, rdfErr.Line, "Test i=%d: %v", i, err)
+		require.Equal(t, tt.column, rdfErr.Column, "Test i=%d: %v", i, err)
+	}
+}
+
+func TestValidateRDF(t *testing.T) {
+	require.NoError(t, ndgo.SetRDF(`_:a <name> "a" .`+"\n"+`uid(v) <friend> <0x1> .`).Validate())
+	require.NoError(t, ndgo.Query{}.SetPred("_:a", "name", "a").Validate())
+	require.NoError(t, (ndgo.Query{}.DeleteNode("0x1") + ndgo.Query{}.DeleteEdge("0x1", "friend", "*")).Validate())
+
+	var errData = []struct {
+		set ndgo.SetRDF
+		del ndgo.DeleteRDF
+	}{
+		{set: `<0x1> <friend> * .`},
+		{set: `<0x1> * * .`},
+		{set: `* <name> "a" .`},
+		{set: ndgo.Query{}.SetPred("0x1", "name", `un"escaped`)},
+		{del: `<0x1> * <0x2> .`},
+		{del: `_:a <name> * .`},
+		{del: `* <name> * .`},
+	}
+	for i, tt := range errData {
+		if tt.set != "" {
+			require.Error(t, tt.set.Validate(), "Test i=%d", i)
+		} else {
+			require.Error(t, tt.del.Validate(), "Test i=%d", i)
+		}
+	}
+}
@@ -0,0 +1,259 @@
+package ndgo
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/dgraph-io/dgo/v210/protos/api"
+	log "github.com/ppp225/lvlog"
+)
+
+// --------------------------------------- registry ---------------------------------------
+
+// QueryRegistry holds named, parameterised queries. Queries are validated on registration,
+// and run with QueryWithVars, so parameters are never interpolated into query text.
+// Safe for concurrent use.
+type QueryRegistry struct {
+	// Hook, if set, is called after every Run, i.e. to record metrics or traces per query name
+	Hook func(e QueryEvent)
+
+	mu      sync.RWMutex
+	queries map[string]*RegisteredQuery
+	stats   map[string]*QueryStats
+}
+
+// RegisteredQuery is a validated query template
+type RegisteredQuery struct {
+	Name   string
+	Query  string // compiled query, named after the registration name
+	Params []DQLVarDecl
+}
+
+// QueryEvent describes a single Run of a registered query
+type QueryEvent struct {
+	Name         string
+	Vars         map[string]string
+	DatabaseTime float64 // ms, as in Txn.GetDatabaseTime
+	NetworkTime  float64 // ms, as in Txn.GetNetworkTime
+	Err          error
+}
+
+// QueryStats are cumulative stats of a registered query
+type QueryStats struct {
+	Calls        int64
+	Errors       int64
+	DatabaseTime float64 // ms
+	NetworkTime  float64 // ms
+}
+
+// DefaultQueries is the registry used by RegisterQuery and RunQuery
+var DefaultQueries = NewQueryRegistry()
+
+// NewQueryRegistry creates empty QueryRegistry
+func NewQueryRegistry() *QueryRegistry {
+	return &QueryRegistry{
+		queries: map[string]*RegisteredQuery{},
+		stats:   map[string]*QueryStats{},
+	}
+}
+
+// RegisterQuery registers query in DefaultQueries. See QueryRegistry.Register.
+func RegisterQuery(name string, tmpl QueryDQL) error {
+	return DefaultQueries.Register(name, tmpl)
+}
+
+// RunQuery runs query registered in DefaultQueries. See QueryRegistry.Run.
+func RunQuery(txn *Txn, name string, params map[string]interface{}) (resp *api.Response, err error) {
+	return DefaultQueries.Run(txn, name, params)
+}
+
+// Register validates and registers query under name. Parameters are declared in query header,
+// i.e. `query userByEmail($email: string, $first: int = 10) { ... }`, with types int, float, bool or string,
+// optionally suffixed with `!` for required. The query is linted, and renamed to name, so it's visible in dgraph traces.
+func (v *QueryRegistry) Register(name string, tmpl QueryDQL) error {
+	if name == "" || strings.ContainsAny(name, " \t\n(){}") {
+		return fmt.Errorf("ndgo: invalid query name %q", name)
+	}
+	doc, err := tmpl.Parse()
+	if err != nil {
+		return fmt.Errorf("ndgo: query %s: %w", name, err)
+	}
+	if issues := doc.Lint(nil); len(issues) > 0 {
+		return fmt.Errorf("ndgo: query %s: %s", name, issues[0])
+	}
+	for _, d := range doc.Vars {
+		switch strings.TrimSuffix(d.Type, "!") {
+		case "int", "float", "bool", "string":
+		default:
+			return fmt.Errorf("ndgo: query %s: parameter %s has unsupported type %s", name, d.Name, d.Type)
+		}
+		if d.Default != "" {
+			if _, err := paramValue(d, strings.Trim(d.Default, `"`)); err != nil {
+				return fmt.Errorf("ndgo: query %s: default of %w", name, err)
+			}
+		}
+	}
+	doc.Name = name
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if _, ok := v.queries[name]; ok {
+		return fmt.Errorf("ndgo: query %s already registered", name)
+	}
+	v.queries[name] = &RegisteredQuery{Name: name, Query: doc.String(), Params: doc.Vars}
+	v.stats[name] = &QueryStats{}
+	return nil
+}
+
+// MustRegister is like Register, but panics on error. Use it in package level var declarations or init.
+func (v *QueryRegistry) MustRegister(name string, tmpl QueryDQL) {
+	if err := v.Register(name, tmpl); err != nil {
+		panic(err)
+	}
+}
+
+// Get returns registered query, or nil
+func (v *QueryRegistry) Get(name string) *RegisteredQuery {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	return v.queries[name]
+}
+
+// Names returns sorted names of registered queries
+func (v *QueryRegistry) Names() []string {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	names := make([]string, 0, len(v.queries))
+	for name := range v.queries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Stats returns a copy of cumulative stats of all registered queries
+func (v *QueryRegistry) Stats() map[string]QueryStats {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	res := make(map[string]QueryStats, len(v.stats))
+	for name, s := range v.stats {
+		res[name] = *s
+	}
+	return res
+}
+
+// Vars validates params against query declaration and converts them to QueryWithVars vars.
+// Param keys may be with or without `$` prefix.
+func (v *QueryRegistry) Vars(name string, params map[string]interface{}) (map[string]string, error) {
+	q := v.Get(name)
+	if q == nil {
+		return nil, fmt.Errorf("ndgo: query %s not registered", name)
+	}
+	vars := make(map[string]string, len(params))
+	for key, val := range params {
+		varName := "$" + strings.TrimPrefix(key, "$")
+		var decl *DQLVarDecl
+		for i := range q.Params {
+			if q.Params[i].Name == varName {
+				decl = &q.Params[i]
+			}
+		}
+		if decl == nil {
+			return nil, fmt.Errorf("ndgo: query %s: unknown parameter %s", name, varName)
+		}
+		s, err := paramValue(*decl, val)
+		if err != nil {
+			return nil, fmt.Errorf("ndgo: query %s: %w", name, err)
+		}
+		vars[varName] = s
+	}
+	for _, d := range q.Params {
+		if _, ok := vars[d.Name]; !ok && strings.HasSuffix(d.Type, "!") {
+			return nil, fmt.Errorf("ndgo: query %s: missing required parameter %s", name, d.Name)
+		}
+	}
+	return vars, nil
+}
+
+// Run runs registered query with params via QueryWithVars. Stats are recorded and Hook is called for every run.
+func (v *QueryRegistry) Run(txn *Txn, name string, params map[string]interface{}) (resp *api.Response, err error) {
+	vars, err := v.Vars(name, params)
+	if err != nil {
+		return nil, err
+	}
+	q := v.Get(name)
+	log.Tracef("RunQuery %s: %s\n", name, vars)
+	db, nw := txn.diag.dbms, txn.diag.nwms
+	resp, err = txn.QueryWithVars(q.Query, vars)
+	e := QueryEvent{
+		Name:         name,
+		Vars:         vars,
+		DatabaseTime: txn.diag.dbms - db,
+		NetworkTime:  txn.diag.nwms - nw,
+		Err:          err,
+	}
+
+	v.mu.Lock()
+	s := v.stats[name]
+	s.Calls++
+	if err != nil {
+		s.Errors++
+	}
+	s.DatabaseTime += e.DatabaseTime
+	s.NetworkTime += e.NetworkTime
+	v.mu.Unlock()
+
+	if v.Hook != nil {
+		v.Hook(e)
+	}
+	return resp, err
+}
+
+// paramValue checks that val matches declared type and formats it for QueryWithVars
+func paramValue(d DQLVarDecl, val interface{}) (string, error) {
+	typ := strings.TrimSuffix(d.Type, "!")
+	switch typ {
+	case "string":
+		if s, ok := val.(string); ok {
+			return s, nil
+		}
+	case "int":
+		switch n := val.(type) {
+		case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+			return fmt.Sprint(n), nil
+		case string:
+			if _, err := strconv.ParseInt(n, 10, 64); err == nil {
+				return n, nil
+			}
+			return "", fmt.Errorf("parameter %s of type int got %q", d.Name, n)
+		}
+	case "float":
+		switch n := val.(type) {
+		case float32:
+			return strconv.FormatFloat(float64(n), 'g', -1, 32), nil
+		case float64:
+			return strconv.FormatFloat(n, 'g', -1, 64), nil
+		case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+			return fmt.Sprint(n), nil
+		case string:
+			if _, err := strconv.ParseFloat(n, 64); err == nil {
+				return n, nil
+			}
+			return "", fmt.Errorf("parameter %s of type float got %q", d.Name, n)
+		}
+	case "bool":
+		switch b := val.(type) {
+		case bool:
+			return strconv.FormatBool(b), nil
+		case string:
+			if _, err := strconv.ParseBool(b); err == nil {
+				return b, nil
+			}
+			return "", fmt.Errorf("parameter %s of type bool got %q", d.Name, b)
+		}
+	}
+	return "", fmt.Errorf("parameter %s of type %s got %T", d.Name, typ, val)
+}
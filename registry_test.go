@@ -0,0 +1,98 @@
+package ndgo_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/ppp225/ndgo/v5"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryRegistryRegister(t *testing.T) {
+	var testData = []struct {
+		name string
+		in   ndgo.QueryDQL
+		err  string
+	}{
+		{name: "ok", in: `query x($email: string!, $first: int = 10) { q(func: eq(email, $email), first: $first) { uid } }`},
+		{name: "ok", in: `{ q(func: has(name)) { uid } }`, err: "ndgo: query ok already registered"},
+		{name: "bad name", in: `{ q(func: has(name)) { uid } }`, err: `ndgo: invalid query name "bad name"`},
+		{name: "parse", in: `{ q(func: has(name) { uid } }`, err: `ndgo: query parse: ndgo: dql 1:21: expected ',' or ')', got "{"`},
+		{name: "lint", in: `{ q(func: eq(email, $email)) { uid } }`, err: "ndgo: query lint: 1:21: query variable $email is not declared in query header"},
+		{name: "type", in: `query x($at: datetime) { q(func: eq(at, $at)) { uid } }`, err: "ndgo: query type: parameter $at has unsupported type datetime"},
+		{name: "default", in: `query x($n: int = "x") { q(func: eq(n, $n)) { uid } }`, err: `ndgo: query default: default of parameter $n of type int got "x"`},
+	}
+	reg := ndgo.NewQueryRegistry()
+	for i, tt := range testData {
+		err := reg.Register(tt.name, tt.in)
+		if tt.err == "" {
+			require.NoError(t, err, "Test i=%d", i)
+			continue
+		}
+		require.EqualError(t, err, tt.err, "Test i=%d", i)
+	}
+	require.Equal(t, []string{"ok"}, reg.Names())
+	require.Equal(t, `query ok($email: string!, $first: int = 10) { q(func: eq(email, $email), first: $first) { uid } }`, reg.Get("ok").Query)
+}
+
+func TestQueryRegistryVars(t *testing.T) {
+	reg := ndgo.NewQueryRegistry()
+	reg.MustRegister("q", `query q($s: string!, $i: int, $f: float, $b: bool) { q(func: eq(s, $s), first: $i) @filter(eq(f, $f) AND eq(b, $b)) { uid } }`)
+
+	var testData = []struct {
+		in  map[string]interface{}
+		out map[string]string
+		err string
+	}{
+		{
+			in:  map[string]interface{}{"s": "a", "$i": int64(5), "f": 1.5, "b": true},
+			out: map[string]string{"$s": "a", "$i": "5", "$f": "1.5", "$b": "true"},
+		},
+		{in: map[string]interface{}{"s": "a", "f": 2}, out: map[string]string{"$s": "a", "$f": "2"}},
+		{in: map[string]interface{}{"i": 1}, err: "ndgo: query q: missing required parameter $s"},
+		{in: map[string]interface{}{"s": 1}, err: "ndgo: query q: parameter $s of type string got int"},
+		{in: map[string]interface{}{"s": "a", "i": "x"}, err: `ndgo: query q: parameter $i of type int got "x"`},
+		{in: map[string]interface{}{"s": "a", "x": 1}, err: "ndgo: query q: unknown parameter $x"},
+	}
+	for i, tt := range testData {
+		vars, err := reg.Vars("q", tt.in)
+		if tt.err != "" {
+			require.EqualError(t, err, tt.err, "Test i=%d", i)
+			continue
+		}
+		require.NoError(t, err, "Test i=%d", i)
+		require.Equal(t, tt.out, vars, "Test i=%d", i)
+	}
+	_, err := reg.Vars("missing", nil)
+	require.EqualError(t, err, "ndgo: query missing not registered")
+}
+
+func TestQueryRegistryRun(t *testing.T) {
+	dg := dgNewClient()
+	defer setupTeardown(dg)()
+	txn := ndgo.NewTxnWithoutContext(dg.NewTxn())
+	defer txn.Discard()
+	populateDBSimple(txn, t)
+
+	reg := ndgo.NewQueryRegistry()
+	var events []ndgo.QueryEvent
+	reg.Hook = func(e ndgo.QueryEvent) { events = append(events, e) }
+	reg.MustRegister("byName", ndgo.QueryDQL(`query x($name: string) { q(func: eq(`+predicateName+`, $name)) { uid } }`))
+
+	resp, err := reg.Run(txn, "byName", map[string]interface{}{"name": firstName})
+	require.NoError(t, err)
+	var decode struct {
+		Q []struct {
+			UID string `json:"uid"`
+		} `json:"q"`
+	}
+	require.NoError(t, json.Unmarshal(resp.GetJson(), &decode))
+	require.Len(t, decode.Q, 1)
+
+	require.Len(t, events, 1)
+	require.Equal(t, "byName", events[0].Name)
+	require.Greater(t, events[0].NetworkTime, 0.0)
+	stats := reg.Stats()["byName"]
+	require.Equal(t, int64(1), stats.Calls)
+	require.Equal(t, int64(0), stats.Errors)
+}
@@ -0,0 +1,60 @@
+package ndgo
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dgraph-io/dgo/v210/protos/api"
+)
+
+// --------------------------------------- replace ---------------------------------------
+
+// ReplaceEdges sets edges of [uid] predicate pred of node from to exactly toUIDs, in a single upsert request.
+// Edges not in toUIDs are deleted and only missing ones are added, so kept edges keep their facets.
+// Without toUIDs, all edges are deleted.
+func (v *Txn) ReplaceEdges(from, pred string, toUIDs ...string) (resp *api.Response, err error) {
+	for _, uid := range append([]string{from}, toUIDs...) {
+		if !isUID(uid) {
+			return nil, fmt.Errorf("ndgo: replace edges: invalid uid %q", uid)
+		}
+	}
+	if len(toUIDs) == 0 {
+		return Query{}.DeletePred(from, pred).Run(v)
+	}
+	to := strings.Join(toUIDs, ", ")
+	q := fmt.Sprintf(`{
+  var(func: uid(%s)) {
+    stale as %s @filter(NOT uid(%s))
+    existing as %s
+  }
+  missing as var(func: uid(%s)) @filter(NOT uid(existing))
+}`, from, pred, to, pred, to)
+	return v.Do(&api.Request{
+		Query: q,
+		Mutations: []*api.Mutation{{
+			DelNquads: []byte(NQuad{Subject: from, Predicate: pred, ObjectID: "uid(stale)"}.String()),
+			SetNquads: []byte(NQuad{Subject: from, Predicate: pred, ObjectID: "uid(missing)"}.String()),
+		}},
+	})
+}
+
+// ReplaceValues sets scalar list predicate pred, i.e. [string], of node uid to exactly values, in a single mutation.
+// Dgraph applies deletions of a mutation before sets, so all old values are deleted, and then values are set.
+// Without values, all values are deleted.
+func (v *Txn) ReplaceValues(uid, pred string, values ...string) (resp *api.Response, err error) {
+	if !isUID(uid) {
+		return nil, fmt.Errorf("ndgo: replace values: invalid uid %q", uid)
+	}
+	del := Query{}.DeletePred(uid, pred)
+	if len(values) == 0 {
+		return del.Run(v)
+	}
+	nquads := make([]string, len(values))
+	for i, val := range values {
+		nquads[i] = NQuad{Subject: uid, Predicate: pred, ObjectValue: val}.String()
+	}
+	return v.Mutate(&api.Mutation{
+		DelNquads: []byte(del),
+		SetNquads: []byte(strings.Join(nquads, "\n")),
+	})
+}
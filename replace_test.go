@@ -0,0 +1,99 @@
+package ndgo_test
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"testing"
+
+	"github.com/dgraph-io/dgo/v210/protos/api"
+	"github.com/ppp225/ndgo/v5"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTxnReplaceEdges(t *testing.T) {
+	dg := dgNewClient()
+	defer setupTeardown(dg)()
+	txn := ndgo.NewTxnWithoutContext(dg.NewTxn())
+	defer txn.Discard()
+	uid1, uid2, uid3, uid4 := populateDBComplex(txn, t)
+	assigned, err := setNode("new", fourthName, fourthAttr).Run(txn)
+	require.NoError(t, err)
+	uid5 := assigned.Uids["new"]
+
+	edges := func() (uids []string) {
+		resp, err := ndgo.QueryDQL(`{ q(func: uid(` + uid1 + `)) { ` + predicateEdge + ` { uid } } }`).Run(txn)
+		require.NoError(t, err)
+		var decode struct {
+			Q []struct {
+				Edge []struct {
+					UID string `json:"uid"`
+				} `json:"testEdge"`
+			} `json:"q"`
+		}
+		require.NoError(t, json.Unmarshal(resp.GetJson(), &decode))
+		for _, q := range decode.Q {
+			for _, e := range q.Edge {
+				uids = append(uids, e.UID)
+			}
+		}
+		sort.Strings(uids)
+		return uids
+	}
+	sorted := func(uids ...string) []string {
+		sort.Strings(uids)
+		return uids
+	}
+
+	_, err = ndgo.SetRDF("<" + uid1 + "> <" + predicateEdge + "> <" + uid2 + "> (weight=0.5) .").Run(txn)
+	require.NoError(t, err)
+	_, err = txn.ReplaceEdges(uid1, predicateEdge, uid2, uid5)
+	require.NoError(t, err)
+	require.Equal(t, sorted(uid2, uid5), edges())
+	resp, err := ndgo.QueryDQL(`{ q(func: uid(` + uid1 + `)) { ` + predicateEdge + ` @facets(eq(weight, 0.5)) { uid } } }`).Run(txn)
+	require.NoError(t, err)
+	require.Contains(t, string(resp.GetJson()), uid2, "kept edge should keep its facets")
+	_, err = txn.ReplaceEdges(uid1, predicateEdge, uid3, uid4, uid5)
+	require.NoError(t, err)
+	require.Equal(t, sorted(uid3, uid4, uid5), edges())
+	_, err = txn.ReplaceEdges(uid1, predicateEdge)
+	require.NoError(t, err)
+	require.Empty(t, edges())
+
+	_, err = txn.ReplaceEdges(uid1, predicateEdge, "_:new")
+	require.EqualError(t, err, `ndgo: replace edges: invalid uid "_:new"`)
+}
+
+func TestTxnReplaceValues(t *testing.T) {
+	dg := dgNewClient()
+	defer setupTeardown(dg)()
+	require.NoError(t, dg.Alter(context.Background(), &api.Operation{Schema: `<testTags>: [string] .`}))
+	defer dg.Alter(context.Background(), &api.Operation{DropAttr: "testTags"})
+	txn := ndgo.NewTxnWithoutContext(dg.NewTxn())
+	defer txn.Discard()
+	uid := populateDBSimple(txn, t)
+
+	values := func() []string {
+		resp, err := ndgo.QueryDQL(`{ q(func: uid(` + uid + `)) { testTags } }`).Run(txn)
+		require.NoError(t, err)
+		var decode struct {
+			Q []struct {
+				Tags []string `json:"testTags"`
+			} `json:"q"`
+		}
+		require.NoError(t, json.Unmarshal(resp.GetJson(), &decode))
+		require.Len(t, decode.Q, 1)
+		sort.Strings(decode.Q[0].Tags)
+		return decode.Q[0].Tags
+	}
+
+	_, err := txn.ReplaceValues(uid, "testTags", "a", "b")
+	require.NoError(t, err)
+	require.Equal(t, []string{"a", "b"}, values())
+	_, err = txn.ReplaceValues(uid, "testTags", "b", "c")
+	require.NoError(t, err)
+	require.Equal(t, []string{"b", "c"}, values())
+	_, err = txn.ReplaceValues(uid, "testTags")
+	require.NoError(t, err)
+	require.Empty(t, values())
+}
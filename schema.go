@@ -0,0 +1,282 @@
+package ndgo
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// --------------------------------------- schema definitions ---------------------------------------
+
+// Schema represents dgraph schema, as returned by `schema {}` query or parsed from schema file
+type Schema struct {
+	Predicates []PredicateSchema `json:"schema"`
+	Types      []TypeSchema      `json:"types"`
+}
+
+// PredicateSchema represents a single predicate definition
+type PredicateSchema struct {
+	Predicate  string   `json:"predicate"`
+	Type       string   `json:"type"`
+	List       bool     `json:"list,omitempty"`
+	Index      bool     `json:"index,omitempty"`
+	Tokenizer  []string `json:"tokenizer,omitempty"`
+	Reverse    bool     `json:"reverse,omitempty"`
+	Count      bool     `json:"count,omitempty"`
+	Upsert     bool     `json:"upsert,omitempty"`
+	Lang       bool     `json:"lang,omitempty"`
+	NoConflict bool     `json:"no_conflict,omitempty"`
+}
+
+// TypeSchema represents a single dgraph type definition
+type TypeSchema struct {
+	Name   string      `json:"name"`
+	Fields []TypeField `json:"fields"`
+}
+
+// TypeField is a predicate, which is part of a type
+type TypeField struct {
+	Name string `json:"name"`
+}
+
+// Predicate returns predicate definition by name, or nil if not found
+func (v *Schema) Predicate(name string) *PredicateSchema {
+	for i := range v.Predicates {
+		if v.Predicates[i].Predicate == name {
+			return &v.Predicates[i]
+		}
+	}
+	return nil
+}
+
+// Type returns type definition by name, or nil if not found
+func (v *Schema) Type(name string) *TypeSchema {
+	for i := range v.Types {
+		if v.Types[i].Name == name {
+			return &v.Types[i]
+		}
+	}
+	return nil
+}
+
+// ReversePredicates returns names of all predicates with @reverse directive
+func (v *Schema) ReversePredicates() (preds []string) {
+	for _, p := range v.Predicates {
+		if p.Reverse {
+			preds = append(preds, p.Predicate)
+		}
+	}
+	return preds
+}
+
+// HasTokenizer reports whether predicate is indexed with given tokenizer
+func (v *PredicateSchema) HasTokenizer(tokenizer string) bool {
+	for _, t := range v.Tokenizer {
+		if t == tokenizer {
+			return true
+		}
+	}
+	return false
+}
+
+// --------------------------------------- live schema ---------------------------------------
+
+// Schema queries current dgraph schema using `schema {}`
+func (v *Txn) Schema() (*Schema, error) {
+	resp, err := v.Query(`schema {}`)
+	if err != nil {
+		return nil, err
+	}
+	return ParseSchemaJSON(resp.GetJson())
+}
+
+// ParseSchemaJSON parses `schema {}` query response
+func ParseSchemaJSON(data []byte) (*Schema, error) {
+	var s Schema
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// --------------------------------------- schema file ---------------------------------------
+
+// ParseSchema parses schema in the format used by dgraph Alter, i.e.
+//
+//	<name>: string @index(exact) @lang .
+//	type Person {
+//	  name
+//	}
+func ParseSchema(schema string) (*Schema, error) {
+	s := &Schema{}
+	p := schemaParser{lines: strings.Split(schema, "\n")}
+	for p.next() {
+		line := p.line()
+		switch {
+		case strings.HasPrefix(line, "type ") || strings.HasPrefix(line, "type\t"):
+			t, err := p.parseType()
+			if err != nil {
+				return nil, err
+			}
+			s.Types = append(s.Types, t)
+		default:
+			pred, err := parsePredicateLine(line)
+			if err != nil {
+				return nil, fmt.Errorf("ndgo: schema line %d: %w", p.i+1, err)
+			}
+			s.Predicates = append(s.Predicates, pred)
+		}
+	}
+	return s, nil
+}
+
+type schemaParser struct {
+	lines []string
+	i     int
+	cur   string
+	began bool
+}
+
+// next advances to next non-empty line, with comments removed
+func (v *schemaParser) next() bool {
+	if v.began {
+		v.i++
+	}
+	v.began = true
+	for ; v.i < len(v.lines); v.i++ {
+		line := v.lines[v.i]
+		if idx := strings.IndexByte(line, '#'); idx >= 0 {
+			line = line[:idx]
+		}
+		line = strings.TrimSpace(line)
+		if line != "" {
+			v.cur = line
+			return true
+		}
+	}
+	return false
+}
+
+func (v *schemaParser) line() string {
+	return v.cur
+}
+
+func (v *schemaParser) parseType() (TypeSchema, error) {
+	start := v.i + 1
+	line := strings.TrimSpace(v.cur[len("type"):])
+	open := strings.IndexByte(line, '{')
+	if open < 0 {
+		return TypeSchema{}, fmt.Errorf("ndgo: schema line %d: expected '{' after type name", start)
+	}
+	t := TypeSchema{Name: strings.TrimSpace(line[:open])}
+	if t.Name == "" {
+		return TypeSchema{}, fmt.Errorf("ndgo: schema line %d: missing type name", start)
+	}
+	rest := line[open+1:]
+	for {
+		closed := false
+		if idx := strings.IndexByte(rest, '}'); idx >= 0 {
+			rest, closed = rest[:idx], true
+		}
+		for _, field := range strings.FieldsFunc(rest, func(r rune) bool { return r == ',' || r == '\n' }) {
+			// old style fields contain type, i.e. `name: string`
+			if idx := strings.IndexByte(field, ':'); idx >= 0 {
+				field = field[:idx]
+			}
+			field = strings.Trim(strings.TrimSpace(field), "<>")
+			if field != "" {
+				t.Fields = append(t.Fields, TypeField{Name: field})
+			}
+		}
+		if closed {
+			return t, nil
+		}
+		if !v.next() {
+			return TypeSchema{}, fmt.Errorf("ndgo: schema line %d: type %s is not closed", start, t.Name)
+		}
+		rest = v.cur
+	}
+}
+
+func parsePredicateLine(line string) (PredicateSchema, error) {
+	p := PredicateSchema{}
+	if !strings.HasSuffix(line, ".") {
+		return p, fmt.Errorf("predicate definition must end with '.': %q", line)
+	}
+	line = strings.TrimSpace(strings.TrimSuffix(line, "."))
+	colon := strings.IndexByte(line, ':')
+	if colon < 0 {
+		return p, fmt.Errorf("missing ':' in predicate definition: %q", line)
+	}
+	p.Predicate = strings.Trim(strings.TrimSpace(line[:colon]), "<>")
+	if p.Predicate == "" {
+		return p, fmt.Errorf("missing predicate name: %q", line)
+	}
+	rest := strings.TrimSpace(line[colon+1:])
+
+	typeEnd := strings.IndexByte(rest, '@')
+	if typeEnd < 0 {
+		typeEnd = len(rest)
+	}
+	typ := strings.TrimSpace(rest[:typeEnd])
+	if strings.HasPrefix(typ, "[") && strings.HasSuffix(typ, "]") {
+		p.List = true
+		typ = strings.TrimSpace(typ[1 : len(typ)-1])
+	}
+	if typ == "" {
+		return p, fmt.Errorf("missing type of predicate %s", p.Predicate)
+	}
+	p.Type = typ
+
+	for _, directive := range splitDirectives(rest[typeEnd:]) {
+		name, args := directive, ""
+		if idx := strings.IndexByte(directive, '('); idx >= 0 {
+			name, args = directive[:idx], strings.TrimSuffix(directive[idx+1:], ")")
+		}
+		switch strings.TrimSpace(name) {
+		case "index":
+			p.Index = true
+			for _, tok := range strings.Split(args, ",") {
+				if tok = strings.TrimSpace(tok); tok != "" {
+					p.Tokenizer = append(p.Tokenizer, tok)
+				}
+			}
+		case "reverse":
+			p.Reverse = true
+		case "count":
+			p.Count = true
+		case "upsert":
+			p.Upsert = true
+		case "lang":
+			p.Lang = true
+		case "noconflict":
+			p.NoConflict = true
+		default:
+			return p, fmt.Errorf("unknown directive @%s on predicate %s", name, p.Predicate)
+		}
+	}
+	return p, nil
+}
+
+// splitDirectives splits `@index(exact, term) @lang` into `index(exact, term)` and `lang`
+func splitDirectives(s string) (directives []string) {
+	depth := 0
+	start := -1
+	for i, r := range s {
+		switch {
+		case r == '(':
+			depth++
+		case r == ')':
+			depth--
+		case r == '@' && depth == 0:
+			if start >= 0 {
+				directives = append(directives, strings.TrimSpace(s[start:i]))
+			}
+			start = i + 1
+		}
+	}
+	if start >= 0 {
+		directives = append(directives, strings.TrimSpace(s[start:]))
+	}
+	return directives
+}
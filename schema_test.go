@@ -0,0 +1,56 @@
+package ndgo_test
+
+import (
+	"testing"
+
+	"github.com/ppp225/ndgo/v5"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSchema(t *testing.T) {
+	s, err := ndgo.ParseSchema(`
+		# test schema
+		<testName>: string @index(hash, term) @upsert .
+		testAttribute: string @lang .
+		<testEdge>: [uid] @reverse @count . # edges
+
+		type TestType {
+			testName
+			testAttribute: string
+			<testEdge>
+		}
+		type Empty {}
+	`)
+	require.NoError(t, err)
+	require.Equal(t, []ndgo.PredicateSchema{
+		{Predicate: "testName", Type: "string", Index: true, Tokenizer: []string{"hash", "term"}, Upsert: true},
+		{Predicate: "testAttribute", Type: "string", Lang: true},
+		{Predicate: "testEdge", Type: "uid", List: true, Reverse: true, Count: true},
+	}, s.Predicates)
+	require.Equal(t, []ndgo.TypeSchema{
+		{Name: "TestType", Fields: []ndgo.TypeField{{Name: "testName"}, {Name: "testAttribute"}, {Name: "testEdge"}}},
+		{Name: "Empty"},
+	}, s.Types)
+	require.Equal(t, []string{"testEdge"}, s.ReversePredicates())
+	require.True(t, s.Predicate("testName").HasTokenizer("hash"))
+	require.Nil(t, s.Type("Missing"))
+
+	var errData = []string{
+		`<testName> string .`,
+		`<testName>: string`,
+		`<testName>: string @unknown .`,
+		`type TestType {
+			testName`,
+	}
+	for i, in := range errData {
+		_, err := ndgo.ParseSchema(in)
+		require.Error(t, err, "Test i=%d", i)
+	}
+}
+
+func TestParseSchemaJSON(t *testing.T) {
+	s, err := ndgo.ParseSchemaJSON([]byte(`{"schema":[{"predicate":"testEdge","type":"uid","list":true,"reverse":true}],"types":[{"name":"TestType","fields":[{"name":"testEdge"}]}]}`))
+	require.NoError(t, err)
+	require.Equal(t, []string{"testEdge"}, s.ReversePredicates())
+	require.Equal(t, "testEdge", s.Type("TestType").Fields[0].Name)
+}
@@ -0,0 +1,162 @@
+package ndgo
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dgraph-io/dgo/v210/protos/api"
+)
+
+// --------------------------------------- upsert by ---------------------------------------
+
+// UpsertBy updates node with pred equal to value, or creates it if there is none, in a single upsert request.
+// obj is marshalled to json, its uid is replaced with uid(v0), pred is set to value and dgraph.type
+// is set to the struct name, if obj has no type. Returns uid of the updated or created node.
+// pred should be indexed for eq. If more nodes match, all of them are updated and the first uid is returned.
+func (v *Txn) UpsertBy(pred string, value interface{}, obj interface{}) (uid string, err error) {
+	item, err := newUpsertItem(obj)
+	if err != nil {
+		return "", err
+	}
+	item[pred] = value
+	uids, err := v.upsert([]string{pred}, item)
+	if err != nil {
+		return "", err
+	}
+	return uids[0], nil
+}
+
+// UpsertByKeys upserts objs in a single upsert request. Each obj is identified by values of its preds,
+// i.e. json fields `name` and `email` for preds ["name", "email"], which must be set. Every obj gets
+// its own var, so keys must be unique among objs. Returns uids in the order of objs, whether created or updated.
+func (v *Txn) UpsertByKeys(preds []string, objs ...interface{}) (uids []string, err error) {
+	if len(preds) == 0 {
+		return nil, fmt.Errorf("ndgo: upsert needs at least one key predicate")
+	}
+	items := make([]map[string]interface{}, len(objs))
+	for i, obj := range objs {
+		if items[i], err = newUpsertItem(obj); err != nil {
+			return nil, err
+		}
+	}
+	return v.upsert(preds, items...)
+}
+
+func (v *Txn) upsert(preds []string, items ...map[string]interface{}) (uids []string, err error) {
+	if len(items) == 0 {
+		return nil, nil
+	}
+	q, vars, err := upsertQuery(preds, items)
+	if err != nil {
+		return nil, err
+	}
+	for i, item := range items {
+		item["uid"] = fmt.Sprintf("uid(v%d)", i)
+	}
+	mutation, err := json.Marshal(items)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := v.Do(&api.Request{
+		Query:     q,
+		Vars:      vars,
+		Mutations: []*api.Mutation{{SetJson: mutation}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var existing map[string][]struct {
+		UID string `json:"uid"`
+	}
+	if err := json.Unmarshal(resp.GetJson(), &existing); err != nil {
+		return nil, err
+	}
+	uids = make([]string, len(items))
+	for i := range items {
+		if found := existing[fmt.Sprintf("u%d", i)]; len(found) > 0 {
+			uids[i] = found[0].UID
+		} else if uids[i] = resp.GetUids()[fmt.Sprintf("uid(v%d)", i)]; uids[i] == "" {
+			return nil, fmt.Errorf("ndgo: upsert returned no uid for object %d", i)
+		}
+	}
+	return uids, nil
+}
+
+// newUpsertItem marshals obj to json object, and sets dgraph.type to struct name, if it's not set
+func newUpsertItem(obj interface{}) (map[string]interface{}, error) {
+	b, err := Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var item map[string]interface{}
+	if err := dec.Decode(&item); err != nil || item == nil {
+		return nil, fmt.Errorf("ndgo: upsert object must marshal to json object, got %T", obj)
+	}
+	if typ, ok := item["dgraph.type"]; !ok || typ == "" {
+		t := reflect.TypeOf(obj)
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		if t.Kind() == reflect.Struct && t.Name() != "" {
+			item["dgraph.type"] = t.Name()
+		}
+	}
+	return item, nil
+}
+
+// upsertQuery builds query, which assigns var vN to nodes matching keys of N-th item, and returns their uids in block uN
+func upsertQuery(preds []string, items []map[string]interface{}) (q string, vars map[string]string, err error) {
+	vars = map[string]string{}
+	var decl []string
+	var b strings.Builder
+	for i, item := range items {
+		var filters []string
+		for j, pred := range preds {
+			val, err := upsertKeyValue(item[pred])
+			if err != nil {
+				return "", nil, fmt.Errorf("ndgo: upsert object %d key %s: %w", i, pred, err)
+			}
+			name := fmt.Sprintf("$k%d_%d", i, j)
+			vars[name] = val
+			decl = append(decl, name+": string")
+			filters = append(filters, fmt.Sprintf("eq(%s, %s)", pred, name))
+		}
+		fmt.Fprintf(&b, "  u%d(func: %s)", i, filters[0])
+		if len(filters) > 1 {
+			fmt.Fprintf(&b, " @filter(%s)", strings.Join(filters[1:], " AND "))
+		}
+		fmt.Fprintf(&b, " {\n    v%d as uid\n  }\n", i)
+	}
+	return fmt.Sprintf("query upsert(%s) {\n%s}", strings.Join(decl, ", "), b.String()), vars, nil
+}
+
+// upsertKeyValue formats key value as query variable
+func upsertKeyValue(val interface{}) (string, error) {
+	switch v := val.(type) {
+	case nil:
+		return "", fmt.Errorf("value is not set")
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return fmt.Sprint(v), nil
+	case float32:
+		return strconv.FormatFloat(float64(v), 'g', -1, 32), nil
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64), nil
+	case time.Time:
+		return v.Format(time.RFC3339Nano), nil
+	}
+	return "", fmt.Errorf("unsupported value type %T", val)
+}
@@ -0,0 +1,50 @@
+package ndgo_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/ppp225/ndgo/v5"
+	"github.com/stretchr/testify/require"
+)
+
+type TestType struct {
+	UID  string `json:"uid,omitempty"`
+	Name string `json:"testName,omitempty"`
+	Attr string `json:"testAttribute,omitempty"`
+}
+
+func TestTxnUpsertBy(t *testing.T) {
+	dg := dgNewClient()
+	defer setupTeardown(dg)()
+	txn := ndgo.NewTxnWithoutContext(dg.NewTxn())
+	defer txn.Discard()
+
+	uid, err := txn.UpsertBy(predicateName, firstName, TestType{Attr: firstAttr})
+	require.NoError(t, err)
+	require.NotEmpty(t, uid)
+	uid2, err := txn.UpsertBy(predicateName, firstName, &TestType{Attr: secondAttr})
+	require.NoError(t, err)
+	require.Equal(t, uid, uid2, "existing node should be updated")
+
+	uids, err := txn.UpsertByKeys([]string{predicateName, predicateAttr},
+		TestType{Name: firstName, Attr: secondAttr},
+		TestType{Name: secondName, Attr: secondAttr},
+		TestType{Name: thirdName, Attr: thirdAttr},
+	)
+	require.NoError(t, err)
+	require.Len(t, uids, 3)
+	require.Equal(t, uid, uids[0])
+	require.NotEqual(t, uids[1], uids[2])
+
+	resp, err := ndgo.QueryDQL(`{ q(func: type(` + testType + `)) { uid ` + predicateName + ` ` + predicateAttr + ` } }`).Run(txn)
+	require.NoError(t, err)
+	var decode struct {
+		Q []TestType `json:"q"`
+	}
+	require.NoError(t, json.Unmarshal(resp.GetJson(), &decode))
+	require.Len(t, decode.Q, 3, "dgraph.type should be set to struct name")
+
+	_, err = txn.UpsertByKeys([]string{predicateName}, TestType{Attr: firstAttr})
+	require.EqualError(t, err, "ndgo: upsert object 0 key testName: value is not set")
+}
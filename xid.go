@@ -0,0 +1,179 @@
+package ndgo
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// --------------------------------------- xid map ---------------------------------------
+
+// XidMap maps external ids to uids. External ids are stored in Pred, which must be indexed for eq,
+// preferably with @upsert, so concurrent transactions creating the same xid conflict.
+// Mappings are cached in-process: committed ones right away, ones created in a Txn once it commits.
+// Safe for concurrent use.
+type XidMap struct {
+	Pred      string // xid predicate, default `xid`
+	Type      string // dgraph.type of created nodes, none if empty
+	BatchSize int    // xids per query, default 1000
+
+	mu   sync.RWMutex
+	uids map[string]string
+}
+
+// NewXidMap creates XidMap for xid predicate pred, which sets dgraph.type of created nodes to typ, if not empty
+func NewXidMap(pred, typ string) *XidMap {
+	return &XidMap{
+		Pred: pred,
+		Type: typ,
+		uids: map[string]string{},
+	}
+}
+
+// Assign returns uids of xids, creating nodes for xids which don't exist yet in txn.
+// Returned map can be used to build edges in the same txn.
+func (v *XidMap) Assign(txn *Txn, xids ...string) (map[string]string, error) {
+	res := make(map[string]string, len(xids))
+	var missing []string
+	v.mu.RLock()
+	for _, xid := range xids {
+		if xid == "" {
+			v.mu.RUnlock()
+			return nil, fmt.Errorf("ndgo: empty xid")
+		}
+		if uid, ok := v.uids[xid]; ok {
+			res[xid] = uid
+		} else if _, ok := res[xid]; !ok {
+			res[xid] = ""
+			missing = append(missing, xid)
+		}
+	}
+	v.mu.RUnlock()
+	if len(missing) == 0 {
+		return res, nil
+	}
+
+	batchSize := v.BatchSize
+	if batchSize <= 0 {
+		batchSize = 1000
+	}
+	for start := 0; start < len(missing); start += batchSize {
+		end := start + batchSize
+		if end > len(missing) {
+			end = len(missing)
+		}
+		if err := v.assign(txn, missing[start:end], res); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+// Lookup returns cached uid of xid, without querying the database
+func (v *XidMap) Lookup(xid string) (uid string, ok bool) {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	uid, ok = v.uids[xid]
+	return uid, ok
+}
+
+// Forget removes xids from cache, i.e. after their nodes were deleted
+func (v *XidMap) Forget(xids ...string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	for _, xid := range xids {
+		delete(v.uids, xid)
+	}
+}
+
+// assign queries xids, and creates nodes for ones not found. Results are written to res.
+func (v *XidMap) assign(txn *Txn, xids []string, res map[string]string) error {
+	pred := v.pred()
+	quoted := make([]string, len(xids))
+	for i, xid := range xids {
+		quoted[i] = quoteRDF(xid)
+	}
+	resp, err := txn.Query(fmt.Sprintf("{\n  q(func: eq(%s, [%s])) {\n    uid\n    %s\n  }\n}", pred, strings.Join(quoted, ", "), pred))
+	if err != nil {
+		return err
+	}
+	var decode struct {
+		Q []map[string]interface{} `json:"q"`
+	}
+	if err := json.Unmarshal(resp.GetJson(), &decode); err != nil {
+		return err
+	}
+	found := map[string]string{}
+	for _, node := range decode.Q {
+		xid, ok := node[pred].(string)
+		if !ok {
+			return fmt.Errorf("ndgo: xid predicate %s must be string, got %v", pred, node[pred])
+		}
+		uid, _ := node["uid"].(string)
+		found[xid] = uid
+	}
+
+	var nquads []string
+	var blanks []string // xid of N-th blank node
+	committed := map[string]string{}
+	pending := map[string]string{} // found, but created earlier in this txn
+	for _, xid := range xids {
+		if uid, ok := found[xid]; ok {
+			res[xid] = uid
+			if txn.created[uid] {
+				pending[xid] = uid
+			} else {
+				committed[xid] = uid
+			}
+			continue
+		}
+		node := fmt.Sprintf("_:xid%d", len(blanks))
+		blanks = append(blanks, xid)
+		nquads = append(nquads, NQuad{Subject: node, Predicate: pred, ObjectValue: xid}.String())
+		if v.Type != "" {
+			nquads = append(nquads, NQuad{Subject: node, Predicate: "dgraph.type", ObjectValue: v.Type}.String())
+		}
+	}
+	v.cache(committed)
+	if len(pending) > 0 {
+		txn.onCommit = append(txn.onCommit, func() { v.cache(pending) })
+	}
+	if len(blanks) == 0 {
+		return nil
+	}
+
+	resp, err = txn.Setnq(strings.Join(nquads, "\n"))
+	if err != nil {
+		return err
+	}
+	created := make(map[string]string, len(blanks))
+	for i, xid := range blanks {
+		uid := resp.GetUids()[fmt.Sprintf("xid%d", i)]
+		if uid == "" {
+			return fmt.Errorf("ndgo: no uid assigned to xid %s", xid)
+		}
+		res[xid] = uid
+		created[xid] = uid
+	}
+	txn.onCommit = append(txn.onCommit, func() { v.cache(created) })
+	return nil
+}
+
+func (v *XidMap) cache(uids map[string]string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.uids == nil {
+		v.uids = map[string]string{}
+	}
+	for xid, uid := range uids {
+		v.uids[xid] = uid
+	}
+}
+
+func (v *XidMap) pred() string {
+	if v.Pred == "" {
+		return "xid"
+	}
+	return v.Pred
+}
@@ -0,0 +1,44 @@
+package ndgo_test
+
+import (
+	"testing"
+
+	"github.com/ppp225/ndgo/v5"
+	"github.com/stretchr/testify/require"
+)
+
+func TestXidMap(t *testing.T) {
+	dg := dgNewClient()
+	defer setupTeardown(dg)()
+	xids := ndgo.NewXidMap(predicateName, testType)
+
+	txn := ndgo.NewTxnWithoutContext(dg.NewTxn())
+	defer txn.Discard()
+	existing := populateDBSimple(txn, t)
+	uids, err := xids.Assign(txn, firstName, secondName, secondName)
+	require.NoError(t, err)
+	require.Len(t, uids, 2)
+	require.Equal(t, existing, uids[firstName])
+	require.NotEmpty(t, uids[secondName])
+
+	_, ok := xids.Lookup(secondName)
+	require.False(t, ok, "created xids should be cached only after commit")
+	_, ok = xids.Lookup(firstName)
+	require.False(t, ok, "xids of nodes created in txn should be cached only after commit")
+	again, err := xids.Assign(txn, secondName)
+	require.NoError(t, err)
+	require.Equal(t, uids[secondName], again[secondName], "txn should see its own xids")
+
+	_, err = ndgo.Query{}.SetEdge(uids[firstName], predicateEdge, uids[secondName]).Run(txn)
+	require.NoError(t, err)
+	require.NoError(t, txn.Commit())
+	uid, ok := xids.Lookup(secondName)
+	require.True(t, ok)
+	require.Equal(t, uids[secondName], uid)
+	uid, ok = xids.Lookup(firstName)
+	require.True(t, ok)
+	require.Equal(t, existing, uid)
+
+	_, err = xids.Assign(txn, "")
+	require.EqualError(t, err, "ndgo: empty xid")
+}